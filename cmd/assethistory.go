@@ -4,30 +4,86 @@
 package cmd
 
 import (
-	"log"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
 	"github.com/spf13/cobra"
 )
 
+var (
+	histPrint   bool
+	histSince   string
+	histAsset   string
+	histCadence string
+)
+
 // assetHistoryCmd represents the assetHistory command
 var assetHistoryCmd = &cobra.Command{
 	Use:   "assetHistory [order number]",
 	Short: "Get the list of completed asset downloads for the given order number",
 	Example: "viya4-orders-cli assetHistory 993456\n" +
 		"viya4-orders-cli ah 993456\n" +
-		"viya4-orders-cli ah 993456 -p $HOME/sas -n ah_993456",
+		"viya4-orders-cli ah 993456 -p $HOME/sas -n ah_993456\n" +
+		"viya4-orders-cli ah 993456 --print --since 30d --asset deploymentAssets --cadence stable -o csv",
 	Aliases: []string{"ah"},
 	Args:    cobra.RangeArgs(1, 1),
 	Run: func(cmd *cobra.Command, args []string) {
 		ar := assetreqs.New(clientCredsType, token, clientID, clientSecret, "assetHistory", args[0], "", "", "", assetFilePath, assetFileName, outFormat, allowUnsuppd)
-		err := ar.GetAsset()
+
+		// Any of the filters implies --print.
+		if histPrint || histSince != "" || histAsset != "" || histCadence != "" {
+			if toStdout {
+				usageError("--print and the history filters cannot be used with --stdout!")
+			}
+			since, err := parseSince(histSince)
+			if err != nil {
+				usageError(err.Error())
+			}
+			ar = ar.WithHistoryFilter(assetreqs.HistoryFilter{Since: since, Asset: histAsset, Cadence: histCadence})
+		}
+
+		err := withGlobalOptions(ar).GetAsset()
 		if err != nil {
-			log.Fatalln(err)
+			fatal(err)
 		}
 	},
 }
 
 func init() {
+	assetHistoryCmd.Flags().BoolVar(&histPrint, "print", false,
+		"print the downloads in the asset history (as a table with -o text) instead of information about the saved file")
+	assetHistoryCmd.Flags().StringVar(&histSince, "since", "",
+		"only print downloads since the given date (2006-01-02 or RFC 3339) or within the given duration (for example: 72h, 30d)")
+	assetHistoryCmd.Flags().StringVar(&histAsset, "asset", "",
+		"only print downloads of the given asset type (for example: deploymentAssets, license, certificates)")
+	assetHistoryCmd.Flags().StringVar(&histCadence, "cadence", "",
+		"only print downloads at the given cadence name (for example: stable, lts)")
+	addAssetFlags(assetHistoryCmd)
 	rootCmd.AddCommand(assetHistoryCmd)
 }
+
+// parseSince parses the value of --since, which is either a date or a duration before now.
+func parseSince(since string) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	// time.ParseDuration does not know about days.
+	if d, ok := strings.CutSuffix(since, "d"); ok {
+		if n, err := strconv.Atoi(d); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(since); err == nil && d >= 0 {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, errors.New("invalid value " + since + " specified for --since option!")
+}
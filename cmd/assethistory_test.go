@@ -0,0 +1,53 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	for _, tc := range []struct {
+		since string
+		want  time.Time
+	}{
+		{since: "", want: time.Time{}},
+		// Dates are midnight local time.
+		{since: "2026-02-01", want: time.Date(2026, 2, 1, 0, 0, 0, 0, time.Local)},
+		{since: "2026-02-01T08:30:00Z", want: time.Date(2026, 2, 1, 8, 30, 0, 0, time.UTC)},
+		{since: "2026-02-01T08:30:00-05:00", want: time.Date(2026, 2, 1, 13, 30, 0, 0, time.UTC)},
+	} {
+		got, err := parseSince(tc.since)
+		if err != nil || !got.Equal(tc.want) {
+			t.Errorf("parseSince(%q) returned %v, %v, want %v", tc.since, got, err, tc.want)
+		}
+	}
+
+	// Durations are before now, and days are calendar days.
+	for _, tc := range []struct {
+		since string
+		want  func(now time.Time) time.Time
+	}{
+		{since: "7d", want: func(now time.Time) time.Time { return now.AddDate(0, 0, -7) }},
+		{since: "0d", want: func(now time.Time) time.Time { return now }},
+		{since: "36h", want: func(now time.Time) time.Time { return now.Add(-36 * time.Hour) }},
+		{since: "1h30m", want: func(now time.Time) time.Time { return now.Add(-90 * time.Minute) }},
+		{since: "0s", want: func(now time.Time) time.Time { return now }},
+	} {
+		before := time.Now()
+		got, err := parseSince(tc.since)
+		after := time.Now()
+		if err != nil || got.Before(tc.want(before)) || got.After(tc.want(after)) {
+			t.Errorf("parseSince(%q) returned %v, %v, want %v", tc.since, got, err, tc.want(before))
+		}
+	}
+
+	for _, since := range []string{"yesterday", "-7d", "-1h", "1.5d", "7days", "d", "7", "2026-13-01", "2026-02-30",
+		"02/01/2026", "2026-02-01 08:30"} {
+		if got, err := parseSince(since); err == nil {
+			t.Errorf("parseSince(%q) returned %v and no error", since, got)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/sassoftware/viya4-orders-cli/lib/cache"
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local asset cache",
+	// The cache subcommands do not call the API, so they do not need to authenticate.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// openCache returns the local asset cache in the directory given by the caller.
+func openCache() *cache.Cache {
+	c, err := cache.New(cacheDir)
+	if err != nil {
+		fatal(err)
+	}
+	return c
+}
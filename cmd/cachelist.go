@@ -0,0 +1,57 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/spf13/cobra"
+)
+
+// cacheListCmd represents the cache list command
+var cacheListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the assets in the local asset cache, most recently used first",
+	Example: "viya4-orders-cli cache list\n" + "viya4-orders-cli cache list -o json",
+	Aliases: []string{"ls"},
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := cacheList()
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheListCmd)
+}
+
+// cacheList prints the cached assets in the output format given by the caller.
+func cacheList() error {
+	entries, err := openCache().List()
+	if err != nil {
+		return err
+	}
+
+	if !textOutput(outFormat) {
+		return assetreqs.Print(os.Stdout, outFormat, entries, entries)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ORDER\tASSET\tCADENCE\tRELEASE\tSIZE\tLAST USED\tDIGEST")
+	for _, e := range entries {
+		digest := strings.TrimPrefix(e.Digest, "sha256:")
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", e.OrderNumber, e.AssetName, e.Cadence, e.CadenceRelease,
+			e.Size, e.LastUsed.Local().Format("2006-01-02 15:04:05"), digest)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,25 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// cachePathCmd represents the cache path command
+var cachePathCmd = &cobra.Command{
+	Use:     "path",
+	Short:   "Print the directory of the local asset cache",
+	Example: "viya4-orders-cli cache path\n" + "du -sh $(viya4-orders-cli cache path)",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(openCache().Dir())
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cachePathCmd)
+}
@@ -0,0 +1,63 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/cache"
+	"github.com/spf13/cobra"
+)
+
+var pruneOlderThan string
+
+// cachePruneCmd represents the cache prune command
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove assets from the local asset cache - all of them, unless --older-than is given",
+	Example: "viya4-orders-cli cache prune --older-than 30d\n" +
+		"viya4-orders-cli cache prune --older-than 2026-01-01",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		before, err := parseSince(pruneOlderThan)
+		if err != nil {
+			usageError("invalid value " + pruneOlderThan + " specified for --older-than option!")
+		}
+		removed, freed, err := openCache().Prune(before)
+		if err != nil {
+			fatal(err)
+		}
+		err = printPruned(removed, freed)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	cachePruneCmd.Flags().StringVar(&pruneOlderThan, "older-than", "",
+		"only remove assets that were last used before the given date (2006-01-02 or RFC 3339) or longer ago than the "+
+			"given duration (for example: 72h, 30d)")
+	cacheCmd.AddCommand(cachePruneCmd)
+}
+
+// printPruned prints what was removed from the cache in the output format given by the caller.
+func printPruned(removed []cache.Entry, freed int64) error {
+	if !textOutput(outFormat) {
+		return assetreqs.Print(os.Stdout, outFormat, struct {
+			Removed    []cache.Entry `json:"removed" yaml:"removed"`
+			FreedBytes int64         `json:"freedBytes" yaml:"freedBytes"`
+		}{removed, freed}, removed)
+	}
+
+	for _, e := range removed {
+		fmt.Println(strings.TrimSpace("Removed " + e.AssetName + " for order " + e.OrderNumber + " " + e.Cadence + " " +
+			e.CadenceRelease))
+	}
+	fmt.Printf("Removed %d cache entries and freed %d bytes\n", len(removed), freed)
+	return nil
+}
@@ -4,8 +4,6 @@
 package cmd
 
 import (
-	"log"
-
 	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
 	"github.com/spf13/cobra"
 )
@@ -22,13 +20,14 @@ var certificatesCmd = &cobra.Command{
 		// Cadence is not a factor in certs, so we hard-code allowUnsuppd to false for the last argument.
 		ar := assetreqs.New(clientCredsType, token, clientID, clientSecret, "certificates", args[0], "", "", "",
 			assetFilePath, assetFileName, outFormat, false)
-		err := ar.GetAsset()
+		err := withGlobalOptions(ar).GetAsset()
 		if err != nil {
-			log.Fatalln(err)
+			fatal(err)
 		}
 	},
 }
 
 func init() {
+	addAssetFlags(certificatesCmd)
 	rootCmd.AddCommand(certificatesCmd)
 }
@@ -0,0 +1,146 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// configOption describes an option that can be set on the command line, in the environment, or in the config file.
+type configOption struct {
+	key    string // the Viper key, which is also the name of the flag for global options that have one
+	flag   string // for options of a single command, the command and its flag, such as "watch --interval"
+	secret bool   // whether the value must be masked when it is displayed
+}
+
+// configOptions lists every option that the CLI reads through Viper.
+var configOptions = []configOption{
+	{key: "apimClientCredentialsId", secret: true},
+	{key: "apimClientCredentialsSecret", secret: true},
+	{key: "clientCredentialsId", secret: true},
+	{key: "clientCredentialsSecret", secret: true},
+	{key: "file-name"},
+	{key: "file-path"},
+	{key: "output"},
+	{key: "allowUnsupported"},
+	{key: "stdout"},
+	{key: "api-host"},
+	{key: "log-level"},
+	{key: "log-format"},
+	{key: "trace-http"},
+	{key: "proxy", secret: true}, // the proxy URL can include a password
+	{key: "no-proxy"},
+	{key: "ca-file"},
+	{key: "client-cert"},
+	{key: "client-key"},
+	{key: "connect-timeout"},
+	{key: "tls-timeout"},
+	{key: "header-timeout"},
+	{key: "stall-timeout"},
+	{key: "timeout"},
+	{key: "upload"},
+	{key: "s3-endpoint"},
+	{key: "s3-region"},
+	{key: "s3-path-style"},
+	{key: "s3-insecure"},
+	{key: "s3AccessKeyId", secret: true},
+	{key: "s3SecretAccessKey", secret: true},
+	{key: "s3SessionToken", secret: true},
+	{key: "ociUsername", secret: true},
+	{key: "ociPassword", secret: true},
+	{key: "cache"},
+	{key: "cache-dir"},
+	{key: "gitops-repo", flag: "gitops sync --repo"},
+	{key: "gitops-path", flag: "gitops sync --path"},
+	{key: "gitops-branch-per-release", flag: "gitops sync --branch-per-release"},
+	{key: "watch-interval", flag: "watch --interval"},
+	{key: "watch-state-file", flag: "watch --state-file"},
+	{key: "watch-hook", flag: "watch --hook"},
+	{key: "watch-webhook", flag: "watch --webhook"},
+	{key: "watch-metrics-listen", flag: "watch --metrics-listen"},
+	{key: "watch-act-on-first", flag: "watch --act-on-first"},
+	{key: "notifications", secret: true}, // webhook URLs usually include a token
+	{key: "notify-expiry-days"},
+	{key: "serve-listen", flag: "serve --listen"},
+	{key: "serve-tls-cert", flag: "serve --tls-cert"},
+	{key: "serve-tls-key", flag: "serve --tls-key"},
+	{key: "serve-client-ca", flag: "serve --client-ca"},
+	{key: "serveTokens", secret: true},
+}
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the SAS Viya Orders CLI configuration file",
+	// The config subcommands do not call the API, so they do not need to authenticate, and they handle reading the
+	// config file themselves.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
+
+// optionFlag returns the flag that sets the given option, and how it is given on the command line, or nil and "" if
+// the option can only be set in the environment or the config file.
+func optionFlag(o configOption) (*pflag.Flag, string) {
+	if o.flag == "" {
+		if f := rootCmd.PersistentFlags().Lookup(o.key); f != nil && !f.Hidden {
+			return f, "--" + f.Name
+		}
+		return nil, ""
+	}
+	path, name, _ := strings.Cut(o.flag, " --")
+	cmd, _, err := rootCmd.Find(strings.Fields(path))
+	if err != nil {
+		return nil, ""
+	}
+	if f := cmd.Flags().Lookup(name); f != nil {
+		return f, o.flag
+	}
+	return nil, ""
+}
+
+// optionSource returns where the effective value of the given option came from: flag, env, file, or default.
+// This follows the order of precedence that Viper applies.
+func optionSource(o configOption) string {
+	if f, _ := optionFlag(o); f != nil && f.Changed {
+		return "flag"
+	}
+	// Viper ignores empty environment variables.
+	if v, ok := os.LookupEnv(envName(o.key)); ok && v != "" {
+		return "env"
+	}
+	if viper.InConfig(o.key) {
+		return "file"
+	}
+	return "default"
+}
+
+// configFileKeys returns the keys that are present in the config file, if one was found.
+func configFileKeys() ([]string, error) {
+	cfgUsed := viper.ConfigFileUsed()
+	if cfgUsed == "" {
+		return nil, nil
+	}
+
+	// Read the file on its own so that keys which Viper gets from flags and the environment are not included.
+	v := viper.New()
+	v.SetConfigFile(cfgUsed)
+	if filepath.Ext(strings.TrimPrefix(filepath.Base(cfgUsed), ".")) == "" {
+		v.SetConfigType("yaml")
+	}
+	err := v.ReadInConfig()
+	if err != nil {
+		return nil, errors.New("ERROR: problem parsing config file " + cfgUsed + ": " + err.Error())
+	}
+	return v.AllKeys(), nil
+}
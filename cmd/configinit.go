@@ -0,0 +1,282 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/authn"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/term"
+)
+
+// configInitCmd represents the config init command
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Interactively create a configuration file containing your API credentials and default options",
+	Example: "viya4-orders-cli config init\n" +
+		"viya4-orders-cli config init -c $HOME/sas/viya4-orders-cli.json",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := configInit(os.Stdin)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configInitCmd)
+}
+
+// configInit prompts for the configuration values, read from the given reader, and writes them to the config file.
+// Nothing is read ahead of the answer to each question, since secrets are read straight from a terminal.
+func configInit(in io.Reader) error {
+	cfgPath, err := configInitPath()
+	if err != nil {
+		return err
+	}
+
+	// Determine the format of the config file. A file with no extension must be in YAML format, but since JSON is
+	// valid YAML we can offer both.
+	// The leading dot of a hidden file such as the default .viya4-orders-cli does not start an extension.
+	cfgFmt := strings.TrimPrefix(strings.ToLower(filepath.Ext(strings.TrimPrefix(filepath.Base(cfgPath), "."))), ".")
+	switch cfgFmt {
+	case "yaml", "yml", "json":
+	case "":
+		cfgFmt, err = promptChoice(in, "Config file format", []string{"yaml", "json"}, "yaml")
+		if err != nil {
+			return err
+		}
+	default:
+		return errors.New("ERROR: config init can only write YAML or JSON config files, not " + cfgPath)
+	}
+
+	if _, err := os.Stat(cfgPath); err == nil {
+		ok, err := promptYesNo(in, cfgPath+" already exists. Overwrite it?", false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Config file not written.")
+			return nil
+		}
+	}
+
+	credsType, err := promptChoice(in, "Client credentials type - apim (developer.sas.com) or apigee (apiportal.sas.com)",
+		[]string{"apim", "apigee"}, "apim")
+	if err != nil {
+		return err
+	}
+	cID, err := promptSecret(in, "Client ID")
+	if err != nil {
+		return err
+	}
+	cSec, err := promptSecret(in, "Client Secret")
+	if err != nil {
+		return err
+	}
+
+	var fPath string
+	for {
+		fPath, err = prompt(in, "Default path for downloaded assets (leave blank for the current working directory)", "")
+		if err != nil {
+			return err
+		}
+		if fPath == "" {
+			break
+		}
+		fPath, err = homedir.Expand(fPath)
+		if err != nil {
+			return errors.New("ERROR: homedir.Expand() returned: " + err.Error())
+		}
+		if chk, err := os.Stat(fPath); err == nil && chk.Mode().IsDir() {
+			break
+		}
+		fmt.Fprintln(os.Stderr, fPath+" is not an existing directory.")
+	}
+
+	oFmt, err := promptChoice(in, "Default output format", []string{"text", "json"}, "text")
+	if err != nil {
+		return err
+	}
+
+	test, err := promptYesNo(in, "Test the credentials now?", false)
+	if err != nil {
+		return err
+	}
+	if test {
+		err = testCreds(in, credsType, cID, cSec)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "The credentials were accepted by the SAS Viya Orders API.")
+	}
+
+	// Encode the credentials ourselves so that no end-of-line characters end up in the encoded values.
+	cIDProp, cSecProp := "apimClientCredentialsId", "apimClientCredentialsSecret"
+	if credsType == "apigee" {
+		cIDProp, cSecProp = "clientCredentialsId", "clientCredentialsSecret"
+	}
+	cfg := map[string]string{
+		cIDProp:  base64.StdEncoding.EncodeToString([]byte(cID)),
+		cSecProp: base64.StdEncoding.EncodeToString([]byte(cSec)),
+		"output": oFmt,
+	}
+	if fPath != "" {
+		cfg["file-path"] = fPath
+	}
+
+	var data []byte
+	if cfgFmt == "json" {
+		data, err = json.MarshalIndent(cfg, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(cfg)
+	}
+	if err != nil {
+		return errors.New("ERROR: attempt to encode config file contents failed: " + err.Error())
+	}
+
+	// The file holds credentials, so only the owner may read it. WriteFile only applies the permissions to new files.
+	err = os.WriteFile(cfgPath, data, 0600)
+	if err != nil {
+		return errors.New("ERROR: attempt to write config file " + cfgPath + " failed: " + err.Error())
+	}
+	err = os.Chmod(cfgPath, 0600)
+	if err != nil {
+		return errors.New("ERROR: attempt to set permissions on config file " + cfgPath + " failed: " + err.Error())
+	}
+
+	fmt.Fprintln(os.Stderr, "Config file written to "+cfgPath)
+	return nil
+}
+
+// configInitPath returns the path of the config file to write: the value of --config if given, or the default.
+func configInitPath() (string, error) {
+	if cfgFile != "" {
+		return cfgFile, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", errors.New("ERROR: homedir.Dir() returned: " + err.Error())
+	}
+	return filepath.Join(home, ".viya4-orders-cli"), nil
+}
+
+// testCreds confirms that the SAS Viya Orders API accepts the given client credentials. APIM credentials can only be
+// checked with a request against an order, so we ask for one.
+func testCreds(in io.Reader, credsType, cID, cSec string) error {
+	if credsType == "apigee" {
+		_, err := authn.GetBearerToken(cID, cSec)
+		return err
+	}
+
+	oNum, err := prompt(in, "Order number to test the credentials against", "")
+	if err != nil {
+		return err
+	}
+	if oNum == "" {
+		return errors.New("ERROR: an order number is required to test APIM client credentials")
+	}
+	ar := assetreqs.New(credsType, "", cID, cSec, "assetHistory", oNum, "", "", "", "", "", "", false)
+	return ar.VerifyCreds()
+}
+
+// readLine reads a line from the given reader, one byte at a time so that nothing after the line is read.
+func readLine(in io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := in.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+// prompt asks for a value, returning the default if the answer is blank.
+func prompt(in io.Reader, question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+	ans, err := readLine(in)
+	if err != nil && (err != io.EOF || ans == "") {
+		return "", errors.New("ERROR: attempt to read answer failed: " + err.Error())
+	}
+	ans = strings.TrimSpace(ans)
+	if ans == "" {
+		return def, nil
+	}
+	return ans, nil
+}
+
+// promptChoice asks for one of the given choices until a valid one is given.
+func promptChoice(in io.Reader, question string, choices []string, def string) (string, error) {
+	for {
+		ans, err := prompt(in, question+" ("+strings.Join(choices, ", ")+")", def)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range choices {
+			if strings.EqualFold(ans, c) {
+				return c, nil
+			}
+		}
+		fmt.Fprintln(os.Stderr, ans+" is not a valid choice.")
+	}
+}
+
+// promptYesNo asks a yes or no question.
+func promptYesNo(in io.Reader, question string, def bool) (bool, error) {
+	d := "n"
+	if def {
+		d = "y"
+	}
+	ans, err := promptChoice(in, question, []string{"y", "n"}, d)
+	return ans == "y", err
+}
+
+// promptSecret asks for a value without echoing it when the given reader is a terminal. A blank answer is not accepted.
+func promptSecret(in io.Reader, question string) (string, error) {
+	f, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		ans, err := prompt(in, question, "")
+		if err == nil && ans == "" {
+			err = errors.New("ERROR: a value is required for " + question)
+		}
+		return ans, err
+	}
+
+	for {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+		b, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", errors.New("ERROR: attempt to read " + question + " failed: " + err.Error())
+		}
+		if ans := strings.TrimSpace(string(b)); ans != "" {
+			return ans, nil
+		}
+		fmt.Fprintln(os.Stderr, "A value is required.")
+	}
+}
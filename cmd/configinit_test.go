@@ -0,0 +1,54 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestConfigInit(t *testing.T) {
+	saved := cfgFile
+	t.Cleanup(func() { cfgFile = saved })
+	cfgFile = filepath.Join(t.TempDir(), "viya4-orders-cli.yaml")
+
+	// The answers are read from one reader, and nothing after an answer is read ahead of the next question.
+	in := strings.NewReader("apigee\nmy id\n  my secret  \n\njson\nn\nleft over\n")
+	if err := configInit(in); err != nil {
+		t.Fatalf("configInit returned %v", err)
+	}
+	if in.Len() != len("left over\n") {
+		t.Errorf("configInit left %d bytes unread", in.Len())
+	}
+
+	b, err := os.ReadFile(cfgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg map[string]string
+	if err = yaml.Unmarshal(b, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg["clientCredentialsId"] != base64.StdEncoding.EncodeToString([]byte("my id")) ||
+		cfg["clientCredentialsSecret"] != base64.StdEncoding.EncodeToString([]byte("my secret")) ||
+		cfg["output"] != "json" || len(cfg) != 3 {
+		t.Errorf("configInit wrote\n%s", b)
+	}
+	if fi, err := os.Stat(cfgFile); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("configInit wrote the file with the mode %v (%v)", fi.Mode(), err)
+	}
+
+	// A blank secret is not accepted, and answers that end without a newline are.
+	if err = configInit(strings.NewReader("y\napim\nid\n\n")); err == nil {
+		t.Error("configInit accepted a blank client secret")
+	}
+	if err = configInit(strings.NewReader("y\napim\nid\nsecret\n\ntext\nn")); err != nil {
+		t.Errorf("configInit of answers without a final newline returned %v", err)
+	}
+}
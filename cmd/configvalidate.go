@@ -0,0 +1,140 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file, environment, and flags for problems and report all of them",
+	Example: "viya4-orders-cli config validate\n" +
+		"viya4-orders-cli config validate -c $HOME/sas/viya4-orders-cli.yaml",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		problems := configValidate()
+		if problems == nil {
+			problems = []string{}
+		}
+
+		// An output format that is not valid is one of the problems, so they are reported as text.
+		oFmt := viper.GetString("output")
+		if !textOutput(oFmt) && assetreqs.CheckOutputFormat(oFmt) == nil {
+			result := struct {
+				Valid    bool     `json:"valid" yaml:"valid"`
+				Problems []string `json:"problems" yaml:"problems"`
+			}{len(problems) == 0, problems}
+			if err := assetreqs.Print(os.Stdout, oFmt, result, result); err != nil {
+				return err
+			}
+		} else if len(problems) == 0 {
+			fmt.Println("The configuration is valid.")
+		} else {
+			for _, p := range problems {
+				fmt.Println("ERROR: " + p)
+			}
+		}
+
+		if len(problems) > 0 {
+			return &kindError{kind: errConfig, err: errors.New("ERROR: the configuration is not valid"), reported: true}
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
+
+// configValidate returns a description of every problem found in the configuration.
+func configValidate() (problems []string) {
+	err := loadConfig()
+	if err != nil {
+		// We cannot look at the config file, but the environment and flags can still be checked.
+		problems = append(problems, strings.TrimPrefix(err.Error(), "ERROR: "))
+	} else {
+		keys, err := configFileKeys()
+		if err != nil {
+			problems = append(problems, strings.TrimPrefix(err.Error(), "ERROR: "))
+		}
+		for _, k := range keys {
+			if !knownOption(k) {
+				problems = append(problems, "unknown option "+k+" in config file "+viper.ConfigFileUsed())
+			}
+		}
+	}
+
+	problems = append(problems, validateOptions()...)
+
+	// Check that there is a usable pair of client credentials.
+	var credsFound bool
+	for _, pair := range [][2]string{
+		{"apimClientCredentialsId", "apimClientCredentialsSecret"},
+		{"clientCredentialsId", "clientCredentialsSecret"},
+	} {
+		if viper.GetString(pair[0]) != "" && viper.GetString(pair[1]) != "" {
+			credsFound = true
+		}
+		for _, prop := range pair {
+			if _, err := decodeCred(prop); err != nil {
+				problems = append(problems, strings.TrimPrefix(err.Error(), "ERROR: "))
+			}
+		}
+	}
+	if !credsFound {
+		problems = append(problems, "no client credentials found - set apimClientCredentialsId and "+
+			"apimClientCredentialsSecret (or clientCredentialsId and clientCredentialsSecret) in the config file, "+
+			"or APIMCLIENTCREDENTIALSID and APIMCLIENTCREDENTIALSSECRET (or CLIENTCREDENTIALSID and "+
+			"CLIENTCREDENTIALSSECRET) in the environment, or give the config file that sets them with -c, --config")
+	}
+
+	return append(problems, validateCommandOptions()...)
+}
+
+// validateCommandOptions returns a description of every problem found in the options of single commands, which the
+// commands only check when they run.
+func validateCommandOptions() (problems []string) {
+	if d := viper.GetDuration("watch-interval"); d <= 0 {
+		problems = append(problems, "invalid value "+viper.GetString("watch-interval")+
+			" specified for watch --interval option! (expected a positive duration, such as 6h)")
+	}
+	if hook := viper.GetString("watch-webhook"); hook != "" {
+		if u, err := url.Parse(hook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "invalid value "+hook+" specified for watch --webhook option! (expected an "+
+				"http or https URL)")
+		}
+	}
+	if (viper.GetString("serve-tls-cert") == "") != (viper.GetString("serve-tls-key") == "") {
+		problems = append(problems, "serve --tls-cert and serve --tls-key must be given together!")
+	}
+	for _, key := range []string{"serve-tls-cert", "serve-tls-key", "serve-client-ca"} {
+		if file := viper.GetString(key); file != "" {
+			if _, err := os.Stat(file); err != nil {
+				problems = append(problems, file+" does not exist and therefore is not a valid value for serve --"+
+					strings.TrimPrefix(key, "serve-")+" option!")
+			}
+		}
+	}
+	return problems
+}
+
+// knownOption reports whether the given config file key is an option that the CLI reads. Viper lowercases keys.
+func knownOption(key string) bool {
+	for _, o := range configOptions {
+		if strings.EqualFold(o.key, key) {
+			return true
+		}
+	}
+	return false
+}
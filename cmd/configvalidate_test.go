@@ -0,0 +1,97 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestOptionFlag(t *testing.T) {
+	for _, tc := range []struct {
+		key  string
+		want string
+	}{
+		{key: "output", want: "--output"},
+		{key: "watch-interval", want: "watch --interval"},
+		{key: "gitops-branch-per-release", want: "gitops sync --branch-per-release"},
+		{key: "serve-client-ca", want: "serve --client-ca"},
+		// Options that are only set in the environment or the config file have no flag.
+		{key: "apimClientCredentialsId"},
+		{key: "notifications"},
+	} {
+		i := slices.IndexFunc(configOptions, func(o configOption) bool { return o.key == tc.key })
+		if i < 0 {
+			t.Errorf("%s is not a config option", tc.key)
+			continue
+		}
+		f, got := optionFlag(configOptions[i])
+		if got != tc.want || (f == nil) != (tc.want == "") {
+			t.Errorf("optionFlag(%s) returned %v, %q, want %q", tc.key, f, got, tc.want)
+		}
+	}
+
+	// Every option of a single command names a flag that the command has.
+	for _, o := range configOptions {
+		if f, _ := optionFlag(o); o.flag != "" && f == nil {
+			t.Errorf("%s names the flag %s, which does not exist", o.key, o.flag)
+		}
+	}
+}
+
+func TestValidateCommandOptions(t *testing.T) {
+	keys := []string{"watch-interval", "watch-webhook", "serve-tls-cert", "serve-tls-key", "serve-client-ca"}
+	saved := make([]any, len(keys))
+	for i, key := range keys {
+		saved[i] = viper.Get(key)
+	}
+	t.Cleanup(func() {
+		for i, key := range keys {
+			viper.Set(key, saved[i])
+		}
+	})
+	set := func(values ...any) {
+		for i, key := range keys {
+			viper.Set(key, values[i])
+		}
+	}
+	cert := filepath.Join(t.TempDir(), "tls.crt")
+	if err := os.WriteFile(cert, []byte("cert"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "tls.key")
+
+	for _, tc := range []struct {
+		name   string
+		values []any
+		want   []string // a part of each problem that is expected
+	}{
+		{name: "defaults", values: []any{"6h", "", "", "", ""}},
+		{name: "all set", values: []any{"30m", "https://hooks.example.com/T000", cert, cert, cert}},
+		{name: "bad interval", values: []any{"0s", "", "", "", ""}, want: []string{"watch --interval"}},
+		{name: "bad webhook", values: []any{"6h", "hooks.example.com", "", "", ""}, want: []string{"watch --webhook"}},
+		{name: "certificate without a key", values: []any{"6h", "", cert, "", ""},
+			want: []string{"must be given together"}},
+		{name: "missing files", values: []any{"6h", "", cert, missing, missing},
+			want: []string{missing + " does not exist and therefore is not a valid value for serve --tls-key",
+				missing + " does not exist and therefore is not a valid value for serve --client-ca"}},
+	} {
+		set(tc.values...)
+		problems := validateCommandOptions()
+		if len(problems) != len(tc.want) {
+			t.Errorf("%s: validateCommandOptions returned %q", tc.name, problems)
+			continue
+		}
+		for i, want := range tc.want {
+			if !strings.Contains(problems[i], want) {
+				t.Errorf("%s: validateCommandOptions returned %q, want a problem with %q", tc.name, problems[i], want)
+			}
+		}
+	}
+}
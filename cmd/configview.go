@@ -0,0 +1,84 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configViewCmd represents the config view command
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Show the effective value of each option and whether it came from a flag, the environment, the config file, or the default",
+	Example: "viya4-orders-cli config view\n" +
+		"viya4-orders-cli config view -c $HOME/sas/viya4-orders-cli.yaml -o json",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := loadConfig()
+		if err != nil {
+			fatal(err)
+		}
+		err = configView()
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configViewCmd)
+}
+
+// viewedOption is an option as displayed by config view.
+type viewedOption struct {
+	Option string `json:"option" yaml:"option"`
+	Flag   string `json:"flag" yaml:"flag"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+// configView prints the effective options in the output format given by the caller.
+func configView() error {
+	cfgUsed := viper.ConfigFileUsed()
+	var opts []viewedOption
+	for _, o := range configOptions {
+		val := viper.GetString(o.key)
+		if val == "" && viper.IsSet(o.key) {
+			// Lists, such as notifications, have no string form.
+			if b, err := json.Marshal(viper.Get(o.key)); err == nil {
+				val = string(b)
+			}
+		}
+		if o.secret && val != "" {
+			val = "********"
+		}
+		_, flag := optionFlag(o)
+		opts = append(opts, viewedOption{Option: o.key, Flag: flag, Value: val, Source: optionSource(o)})
+	}
+
+	if oFmt := viper.GetString("output"); !textOutput(oFmt) {
+		return assetreqs.Print(os.Stdout, oFmt, struct {
+			ConfigFile string         `json:"configFile" yaml:"configFile"`
+			Options    []viewedOption `json:"options" yaml:"options"`
+		}{cfgUsed, opts}, opts)
+	}
+
+	if cfgUsed == "" {
+		cfgUsed = "(none found)"
+	}
+	fmt.Println("ConfigFile: " + cfgUsed)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "OPTION\tFLAG\tVALUE\tSOURCE")
+	for _, o := range opts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", o.Option, o.Flag, o.Value, o.Source)
+	}
+	return tw.Flush()
+}
@@ -4,10 +4,19 @@
 package cmd
 
 import (
-	"log"
+	"slices"
+	"strings"
 
 	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/ocipush"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	pushDest      string
+	pushWith      []string
+	pushPlainHTTP bool
 )
 
 // deploymentAssetsCmd represents the deploymentAssets command
@@ -17,7 +26,8 @@ var deploymentAssetsCmd = &cobra.Command{
 		" if version not specified, get the latest version of the given cadence name",
 	Example: "viya4-orders-cli depassets 993456 stable 2025.01\n" +
 		"viya4-orders-cli dep 993456 stable\n" +
-		"viya4-orders-cli dep 993456 stable -p $HOME/sas -n depAssets_993456_stable_2025_01",
+		"viya4-orders-cli dep 993456 stable -p $HOME/sas -n depAssets_993456_stable_2025_01\n" +
+		"viya4-orders-cli dep 993456 stable 2025.01 --push oci://registry.example.com/sas/assets --push-with license,certificates",
 	Aliases: []string{"depassets", "dep"},
 	Args:    cobra.RangeArgs(2, 4),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -30,13 +40,114 @@ var deploymentAssetsCmd = &cobra.Command{
 			crel = args[3]
 		}
 		ar := assetreqs.New(clientCredsType, token, clientID, clientSecret, "deploymentAssets", args[0], args[1], cver, crel, assetFilePath, assetFileName, outFormat, allowUnsuppd)
-		err := ar.GetAsset()
+
+		if pushDest == "" {
+			if len(pushWith) > 0 {
+				usageError("--push-with can only be used with --push!")
+			}
+			err := withGlobalOptions(ar).GetAsset()
+			if err != nil {
+				fatal(err)
+			}
+			return
+		}
+
+		if toStdout {
+			usageError("--push cannot be used with --stdout!")
+		}
+		for _, a := range pushWith {
+			if a != "license" && a != "certificates" {
+				usageError("invalid value " + a + " specified for --push-with option! (expected license or certificates)")
+			}
+		}
+		if cver == "" && slices.Contains(pushWith, "license") {
+			usageError("a cadence version is required to push the license with the deployment assets!")
+		}
+		p, err := ocipush.New(pushDest, ocipush.Config{
+			Username:  viper.GetString("ociUsername"),
+			Password:  viper.GetString("ociPassword"),
+			PlainHTTP: pushPlainHTTP,
+		})
+		if err != nil {
+			usageError("invalid value " + pushDest + " specified for --push option! (" + strings.TrimPrefix(err.Error(), "ERROR: ") + ")")
+		}
+		pushAssets := slices.Compact(slices.Sorted(slices.Values(pushWith)))
+
+		if dryRun {
+			err := withGlobalOptions(ar).GetAsset()
+			if err != nil {
+				fatal(err)
+			}
+			for _, a := range pushAssets {
+				other := pushWithAssetReq(a, args[0], args[1], cver)
+				if err := other.GetAsset(); err != nil {
+					fatal(err)
+				}
+			}
+			return
+		}
+
+		output, err := withGlobalOptions(ar).Fetch()
+		if err != nil {
+			fatal(err)
+		}
+		artifact := ocipush.Artifact{
+			Assets:         []ocipush.Asset{{Name: output.AssetName, Path: output.AssetLocation}},
+			OrderNumber:    output.OrderNumber,
+			Cadence:        output.Cadence,
+			CadenceRelease: output.CadenceRelease,
+		}
+
+		// The other assets are saved next to the deployment assets under their default names.
+		for _, a := range pushAssets {
+			other := pushWithAssetReq(a, args[0], args[1], cver)
+			o, err := other.Fetch()
+			if err != nil {
+				fatal(err)
+			}
+			artifact.Assets = append(artifact.Assets, ocipush.Asset{Name: o.AssetName, Path: o.AssetLocation})
+		}
+
+		output.PushLocation, output.PushDigest, err = p.Push(artifact)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = ar.PrintOutput(output)
 		if err != nil {
-			log.Fatalln(err)
+			fatal(err)
 		}
 	},
 }
 
 func init() {
+	deploymentAssetsCmd.Flags().StringVar(&pushDest, "push", "",
+		"push the deployment assets to the given container registry as an OCI artifact (oci://registry/repository[:tag]); "+
+			"the tag defaults to <cadence>-<release>")
+	deploymentAssetsCmd.Flags().StringSliceVar(&pushWith, "push-with", nil,
+		"also download the given assets and include them in the pushed artifact (license, certificates)")
+	deploymentAssetsCmd.Flags().BoolVar(&pushPlainHTTP, "push-plain-http", false,
+		"use HTTP rather than HTTPS to connect to the registry given by --push")
+	deploymentAssetsCmd.Flags().BoolVar(&ifChanged, "if-changed", false,
+		"do not download the deployment assets if those already saved for the cadence are of the same release - "+
+			"report the saved ones instead")
+	addAssetFlags(deploymentAssetsCmd)
 	rootCmd.AddCommand(deploymentAssetsCmd)
 }
+
+// pushWithAssetReq returns the request for the given asset to push with the deployment assets for the given order,
+// cadence name, and version. The asset is saved next to the deployment assets under its default name, and is requested
+// with the global options, as the deployment assets are.
+func pushWithAssetReq(asset, orderNum, cadenceName, cadenceVer string) assetreqs.AssetReq {
+	var ar assetreqs.AssetReq
+	if asset == "certificates" {
+		// Cadence is not a factor in certs, as for the certificates command.
+		ar = assetreqs.New(clientCredsType, token, clientID, clientSecret, asset, orderNum, "", "", "", assetFilePath, "",
+			outFormat, false)
+	} else {
+		ar = assetreqs.New(clientCredsType, token, clientID, clientSecret, asset, orderNum, cadenceName, cadenceVer, "",
+			assetFilePath, "", outFormat, allowUnsuppd)
+	}
+	// The digest given by --expect-sha256 is that of the deployment assets.
+	return withGlobalOptions(ar).WithExpectedSHA256("")
+}
@@ -0,0 +1,160 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apierrors"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+)
+
+// The exit codes of the CLI, which tell scripts what kind of failure stopped it.
+const (
+	exitError              = 1  // any failure not listed below
+	exitUsage              = 2  // the command line or the config file is not valid
+	exitUnauthorized       = 3  // the SAS Viya Orders API did not accept the credentials
+	exitOrderNotFound      = 4  // the order was not found
+	exitNotFound           = 5  // the order, or the cadence name, version, or release, was not found
+	exitCadenceUnsupported = 6  // the cadence is no longer supported (see --allowUnsupported)
+	exitRateLimited        = 7  // too many requests were made - retry later
+	exitServer             = 8  // the SAS Viya Orders API failed - retry later
+	exitNetwork            = 9  // the SAS Viya Orders API could not be reached, or the download was cut short
+	exitDigestMismatch     = 10 // the asset does not have the digest given by --expect-sha256
+)
+
+// The kinds of failure that are not from the SAS Viya Orders API.
+var (
+	errUsage  = errors.New("invalid command line")
+	errConfig = errors.New("invalid config file")
+)
+
+// errorKinds lists the kinds of failure, with their exit codes, the codes that they are reported with in JSON, and
+// whether trying again later may succeed.
+var errorKinds = []struct {
+	kind      error
+	exit      int
+	code      string
+	retryable bool
+}{
+	{errUsage, exitUsage, "usageError", false},
+	{errConfig, exitUsage, "configError", false},
+	{apierrors.ErrUnauthorized, exitUnauthorized, "unauthorized", false},
+	{apierrors.ErrOrderNotFound, exitOrderNotFound, "orderNotFound", false},
+	{apierrors.ErrNotFound, exitNotFound, "notFound", false},
+	{apierrors.ErrCadenceUnsupported, exitCadenceUnsupported, "cadenceUnsupported", false},
+	{apierrors.ErrRateLimited, exitRateLimited, "rateLimited", true},
+	{apierrors.ErrServer, exitServer, "serverError", true},
+	{apierrors.ErrNetwork, exitNetwork, "networkError", true},
+	{assetreqs.ErrDigestMismatch, exitDigestMismatch, "digestMismatch", false},
+}
+
+// errorOutput is what is printed to STDOUT about a failure when JSON output is selected, so that tools which parse
+// the output of the CLI learn why it failed.
+type errorOutput struct {
+	Code       string `json:"code"`
+	ExitCode   int    `json:"exitCode"`
+	HTTPStatus int    `json:"httpStatus,omitempty"`
+	Message    string `json:"message"`
+	RequestURL string `json:"requestURL,omitempty"`
+	Retryable  bool   `json:"retryable"`
+	RetryAfter int    `json:"retryAfter,omitempty"` // seconds, if the API said how long to wait
+}
+
+// kindError is an error of one of the kinds that are not from the SAS Viya Orders API.
+type kindError struct {
+	kind     error
+	err      error
+	reported bool // whether the command has already reported the failure in full, in its output
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// exitCode returns the exit code for the given error.
+func exitCode(err error) int {
+	return describeError(err).ExitCode
+}
+
+// describeError returns the description of the given error that is printed when JSON output is selected.
+func describeError(err error) errorOutput {
+	eo := errorOutput{Code: "error", ExitCode: exitError, Message: strings.TrimPrefix(err.Error(), "ERROR: ")}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.kind) {
+			eo.Code, eo.ExitCode, eo.Retryable = k.code, k.exit, k.retryable
+			break
+		}
+	}
+	var ae *apierrors.Error
+	var ne *apierrors.NetworkError
+	if errors.As(err, &ae) {
+		eo.HTTPStatus, eo.RequestURL = ae.StatusCode, ae.URL
+		eo.RetryAfter = int(ae.RetryAfter.Seconds())
+	} else if errors.As(err, &ne) {
+		eo.RequestURL = ne.URL
+	}
+	return eo
+}
+
+// jsonOutput returns whether the caller selected JSON output.
+func jsonOutput() bool {
+	oFmt := strings.ToLower(outFormat)
+	return oFmt == "json" || oFmt == "j"
+}
+
+// fatal logs the given error and then exits with the exit code for it.
+func fatal(err error) {
+	if !reported(err) {
+		logError(err)
+	}
+	exit(err)
+}
+
+// reported returns whether the given error has already been reported in full by the command that failed.
+func reported(err error) bool {
+	var ke *kindError
+	return errors.As(err, &ke) && ke.reported
+}
+
+// exit exits with the exit code for the given error, which has already been reported. If JSON output is selected, it
+// first prints a description of the error where information about the asset would have been printed: STDOUT, unless
+// the asset is streamed there.
+func exit(err error) {
+	eo := describeError(err)
+	if jsonOutput() && !reported(err) {
+		b, merr := json.MarshalIndent(eo, "", "\t")
+		if merr != nil {
+			slog.Error("json.MarshalIndent() returned: " + merr.Error())
+			os.Exit(exitError)
+		}
+		w := os.Stdout
+		if toStdout {
+			w = os.Stderr
+		}
+		fmt.Fprintln(w, string(b))
+	}
+	os.Exit(eo.ExitCode)
+}
+
+// usageError prints the given error followed by the tool usage text, and then exits.
+// Essentially, this mimics what Cobra does when it detects a usage error.
+func usageError(message string) {
+	println("Error: " + message)
+	err := rootCmd.Usage()
+	if err != nil {
+		slog.Error("rootCmd.Usage() returned " + err.Error())
+		os.Exit(exitError)
+	}
+	exit(&kindError{kind: errUsage, err: errors.New(message)})
+}
@@ -0,0 +1,89 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/orderstest"
+)
+
+func TestExitCode(t *testing.T) {
+	api, err := orderstest.NewServer(orderstest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.Close()
+	assetreqs.SetAPIHost(api.URL)
+	defer assetreqs.SetAPIHost("")
+
+	for _, tc := range []struct {
+		name                                  string
+		order, asset, cadenceName, cadenceVer string
+		fault                                 *orderstest.Fault
+		expectSHA256                          string
+		exit                                  int
+		code                                  string
+		httpStatus                            int
+	}{
+		{name: "credentials not accepted", order: "923457", asset: "certificates",
+			fault: &orderstest.Fault{Status: http.StatusUnauthorized}, exit: exitUnauthorized, code: "unauthorized",
+			httpStatus: http.StatusUnauthorized},
+		{name: "unknown order", order: "999999", asset: "certificates", exit: exitOrderNotFound,
+			code: "orderNotFound", httpStatus: http.StatusNotFound},
+		{name: "unknown cadence", order: "923457", asset: "license", cadenceName: "stable", cadenceVer: "1999.01",
+			exit: exitNotFound, code: "notFound", httpStatus: http.StatusNotFound},
+		{name: "unsupported cadence", order: "923457", asset: "license", cadenceName: "lts", cadenceVer: "2024.09",
+			exit: exitCadenceUnsupported, code: "cadenceUnsupported", httpStatus: http.StatusBadRequest},
+		{name: "too many requests", order: "923457", asset: "certificates",
+			fault: &orderstest.Fault{Status: http.StatusTooManyRequests}, exit: exitRateLimited, code: "rateLimited",
+			httpStatus: http.StatusTooManyRequests},
+		{name: "server failure", order: "923457", asset: "deploymentAssets", cadenceName: "stable",
+			fault: &orderstest.Fault{Status: http.StatusBadGateway}, exit: exitServer, code: "serverError",
+			httpStatus: http.StatusBadGateway},
+		{name: "truncated body", order: "923457", asset: "deploymentAssets", cadenceName: "stable",
+			fault: &orderstest.Fault{Truncate: true}, exit: exitNetwork, code: "networkError"},
+		{name: "wrong digest", order: "923457", asset: "certificates", expectSHA256: "00", exit: exitDigestMismatch,
+			code: "digestMismatch"},
+	} {
+		api.ClearFaults()
+		if tc.fault != nil {
+			api.InjectFault(*tc.fault)
+		}
+		ar := assetreqs.New("apim", "", "id", "secret", tc.asset, tc.order, tc.cadenceName, tc.cadenceVer, "",
+			t.TempDir(), "", "json", false)
+		if tc.expectSHA256 != "" {
+			ar = ar.WithExpectedSHA256(tc.expectSHA256)
+		}
+		_, err := ar.Fetch()
+		if err == nil {
+			t.Errorf("%s: Fetch succeeded", tc.name)
+			continue
+		}
+		eo := describeError(err)
+		if exitCode(err) != tc.exit || eo.Code != tc.code || eo.HTTPStatus != tc.httpStatus {
+			t.Errorf("%s: %v is reported with exit code %d, code %s, and status %d, want %d, %s, and %d", tc.name,
+				err, exitCode(err), eo.Code, eo.HTTPStatus, tc.exit, tc.code, tc.httpStatus)
+		}
+		if eo.Retryable != (tc.exit == exitRateLimited || tc.exit == exitServer || tc.exit == exitNetwork) {
+			t.Errorf("%s: %v is reported as retryable %t", tc.name, err, eo.Retryable)
+		}
+	}
+
+	for _, tc := range []struct {
+		err  error
+		exit int
+	}{
+		{&kindError{kind: errUsage, err: errors.New("ERROR: invalid value")}, exitUsage},
+		{&kindError{kind: errConfig, err: errors.New("ERROR: invalid config")}, exitUsage},
+		{errors.New("ERROR: anything else"), exitError},
+	} {
+		if exitCode(tc.err) != tc.exit {
+			t.Errorf("%v is reported with exit code %d, want %d", tc.err, exitCode(tc.err), tc.exit)
+		}
+	}
+}
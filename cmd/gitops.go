@@ -0,0 +1,18 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// gitopsCmd represents the gitops command
+var gitopsCmd = &cobra.Command{
+	Use:   "gitops",
+	Short: "Keep deployment assets in a git repository for GitOps tools such as Argo CD and Flux",
+}
+
+func init() {
+	rootCmd.AddCommand(gitopsCmd)
+}
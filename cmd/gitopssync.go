@@ -0,0 +1,143 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/gitops"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// gitopsSyncCmd represents the gitops sync command
+var gitopsSyncCmd = &cobra.Command{
+	Use: "sync [order number] [cadence name] [cadence version] [cadence release]",
+	Short: "Download deployment assets for the given order number at the given cadence name and version, extract " +
+		"sas-bases into a git working tree, and commit it",
+	Example: "viya4-orders-cli gitops sync 993456 stable 2025.01 --repo $HOME/git/viya-gitops --path base/sas-bases\n" +
+		"viya4-orders-cli gitops sync 993456 stable --repo $HOME/git/viya-gitops --branch-per-release",
+	Args: cobra.RangeArgs(2, 4),
+	Run: func(cmd *cobra.Command, args []string) {
+		cver := ""
+		crel := ""
+		if len(args) >= 3 {
+			cver = args[2]
+		}
+		if len(args) == 4 {
+			crel = args[3]
+		}
+		if toStdout {
+			usageError("gitops sync cannot be used with --stdout!")
+		}
+
+		relPath := filepath.Clean(viper.GetString("gitops-path"))
+		if err := gitops.CheckPath(relPath); err != nil {
+			usageError("invalid value " + viper.GetString("gitops-path") + " specified for --path option! (" +
+				strings.TrimPrefix(err.Error(), "ERROR: ") + ")")
+		}
+		repo, err := gitops.Open(viper.GetString("gitops-repo"))
+		if err != nil {
+			fatal(err)
+		}
+		err = repo.CheckClean(relPath)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = gitopsSyncRelease(repo, relPath, args[0], args[1], cver, crel)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	gitopsSyncCmd.Flags().String("repo", ".", "local git working tree to commit the deployment assets to")
+	gitopsSyncCmd.Flags().String("path", "sas-bases",
+		"path inside the git working tree that the contents of sas-bases are extracted to (replacing what is there)")
+	gitopsSyncCmd.Flags().Bool("branch-per-release", false,
+		"commit to a branch named sas-viya/<cadence>-<release>, which is created from the current commit if needed")
+	for key, flag := range map[string]string{
+		"gitops-repo":               "repo",
+		"gitops-path":               "path",
+		"gitops-branch-per-release": "branch-per-release",
+	} {
+		err := viper.BindPFlag(key, gitopsSyncCmd.Flags().Lookup(flag))
+		if err != nil {
+			log.Fatalln("ERROR: viper.BindPFlag() returned: " + err.Error())
+		}
+	}
+	gitopsCmd.AddCommand(gitopsSyncCmd)
+}
+
+// gitopsSyncRelease downloads the deployment assets for the given order number, cadence name, version, and release,
+// commits sas-bases from them to the given path of the repository, and prints the result. The deployment assets are
+// removed afterwards, whether or not that succeeds, unless a file path was given.
+func gitopsSyncRelease(repo *gitops.Repo, relPath, orderNum, cadenceName, cadenceVer, cadenceRel string) error {
+	fPath := assetFilePath
+	if fPath == "" {
+		var err error
+		fPath, err = os.MkdirTemp("", "viya4-orders-cli-")
+		if err != nil {
+			return errors.New("ERROR: attempt to create temporary directory failed: " + err.Error())
+		}
+		defer os.RemoveAll(fPath)
+	}
+	ar := assetreqs.New(clientCredsType, token, clientID, clientSecret, "deploymentAssets", orderNum, cadenceName, cadenceVer, cadenceRel, fPath, assetFileName, outFormat, allowUnsuppd)
+	output, err := withGlobalOptions(ar).Fetch()
+	if err != nil {
+		return err
+	}
+
+	err = gitopsSync(repo, relPath, &output)
+	if err != nil {
+		return err
+	}
+	if assetFilePath == "" {
+		// The tarball is removed, so point at what was extracted from it instead.
+		output.AssetLocation = filepath.Join(repo.Dir(), relPath)
+	}
+	return ar.PrintOutput(output)
+}
+
+// gitopsSync extracts sas-bases from the deployment assets described by the given output struct into the given path
+// of the repository, commits it, and records the result in the output struct.
+func gitopsSync(repo *gitops.Repo, relPath string, output *assetreqs.Output) (err error) {
+	if viper.GetBool("gitops-branch-per-release") {
+		err = repo.SwitchBranch(gitops.BranchName(output.Cadence, output.CadenceRelease))
+		if err != nil {
+			return err
+		}
+	}
+	output.GitBranch, err = repo.CurrentBranch()
+	if err != nil {
+		return err
+	}
+
+	err = gitops.ExtractBases(output.AssetLocation, filepath.Join(repo.Dir(), relPath))
+	if err != nil {
+		return err
+	}
+
+	message := "Update " + relPath + " to " + output.Cadence + " release " + output.CadenceRelease + "\n\n" +
+		"Order-Number: " + output.OrderNumber + "\n" +
+		"Cadence: " + output.Cadence + "\n" +
+		"Cadence-Release: " + output.CadenceRelease + "\n"
+	commit, changed, err := repo.Commit(relPath, message)
+	if err != nil {
+		return err
+	}
+	output.GitCommit = commit
+	output.GitStatus = "committed"
+	if !changed {
+		output.GitStatus = "unchanged"
+	}
+	return nil
+}
@@ -4,8 +4,6 @@
 package cmd
 
 import (
-	"log"
-
 	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
 	"github.com/spf13/cobra"
 )
@@ -20,13 +18,14 @@ var licenseCmd = &cobra.Command{
 	Args:    cobra.ExactArgs(3),
 	Run: func(cmd *cobra.Command, args []string) {
 		ar := assetreqs.New(clientCredsType, token, clientID, clientSecret, "license", args[0], args[1], args[2], "", assetFilePath, assetFileName, outFormat, allowUnsuppd)
-		err := ar.GetAsset()
+		err := withGlobalOptions(ar).GetAsset()
 		if err != nil {
-			log.Fatalln(err)
+			fatal(err)
 		}
 	},
 }
 
 func init() {
+	addAssetFlags(licenseCmd)
 	rootCmd.AddCommand(licenseCmd)
 }
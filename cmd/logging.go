@@ -0,0 +1,60 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// initLogging sends log records at or above the level given by the log-level option to STDERR, in the format given by
+// the log-format option, so that STDOUT only ever holds the output of a command. It is called once the flags are
+// parsed, and again once the config file is read, since that can set the options too.
+func initLogging() {
+	if problems := checkLogOptions(); len(problems) > 0 {
+		usageError(problems[0])
+	}
+
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(viper.GetString("log-level")))
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	if strings.EqualFold(viper.GetString("log-format"), "json") {
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		h = slog.NewTextHandler(os.Stderr, opts)
+	}
+	// This also sends anything logged with the log package through the handler.
+	slog.SetDefault(slog.New(h))
+}
+
+// checkLogOptions checks the log-level and log-format option values in Viper and returns a description of every
+// problem found.
+func checkLogOptions() (problems []string) {
+	var level slog.Level
+	if lvl := viper.GetString("log-level"); level.UnmarshalText([]byte(lvl)) != nil {
+		problems = append(problems, "invalid value "+lvl+" specified for --log-level option! "+
+			"(expected debug, info, warn, or error)")
+	}
+	if f := viper.GetString("log-format"); !strings.EqualFold(f, "text") && !strings.EqualFold(f, "json") {
+		problems = append(problems, "invalid value "+f+" specified for --log-format option! (expected text or json)")
+	}
+	return problems
+}
+
+// logError logs the given error, with what is known about the request that failed, if any.
+func logError(err error) {
+	eo := describeError(err)
+	attrs := []any{"code", eo.Code}
+	if eo.HTTPStatus != 0 {
+		attrs = append(attrs, "status", eo.HTTPStatus)
+	}
+	if eo.RequestURL != "" {
+		attrs = append(attrs, "url", eo.RequestURL)
+	}
+	slog.Error(eo.Message, attrs...)
+}
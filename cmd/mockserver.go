@@ -0,0 +1,82 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/orderstest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockListen       string
+	mockOrders       []string
+	mockClientID     string
+	mockClientSecret string
+	mockFaults       []string
+	mockExpiry       time.Duration
+)
+
+// mockServerCmd represents the mock-server command
+var mockServerCmd = &cobra.Command{
+	Use:    "mock-server",
+	Short:  "Run a mock of the SAS Viya Orders API that serves fabricated order assets, for testing and demos",
+	Hidden: true,
+	Example: "viya4-orders-cli mock-server --listen 127.0.0.1:8089 --order 923457\n" +
+		"viya4-orders-cli mock-server --fault path=deploymentAssets,status=503,times=2 --fault path=certificates,truncate\n" +
+		"VIYA4_ORDERS_API_HOST=http://127.0.0.1:8089 viya4-orders-cli deploymentAssets 923457 stable",
+	Args: cobra.NoArgs,
+	// The mock does not use the config file or call the API.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := orderstest.Config{
+			ClientID:     mockClientID,
+			ClientSecret: mockClientSecret,
+			Orders:       orderstest.DefaultOrders(mockOrders...),
+			Expiry:       mockExpiry,
+		}
+		for _, spec := range mockFaults {
+			f, err := orderstest.ParseFault(spec)
+			if err != nil {
+				usageError(strings.TrimPrefix(err.Error(), "ERROR: "))
+			}
+			cfg.Faults = append(cfg.Faults, f)
+		}
+		api, err := orderstest.New(cfg)
+		if err != nil {
+			fatal(err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		slog.Info("serving mock SAS Viya Orders API", "orders", strings.Join(mockOrders, ","), "address", mockListen)
+		slog.Info("set VIYA4_ORDERS_API_HOST=http://" + mockListen + " to point the CLI at this mock")
+		err = orderstest.ListenAndServe(ctx, mockListen, api)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	mockServerCmd.Flags().StringVar(&mockListen, "listen", "127.0.0.1:8089", "address to listen on")
+	mockServerCmd.Flags().StringSliceVar(&mockOrders, "order", []string{"923457"}, "order numbers to serve")
+	mockServerCmd.Flags().StringVar(&mockClientID, "client-id", "",
+		"client ID to accept, not base64 encoded (any credentials are accepted if neither this nor --client-secret is set)")
+	mockServerCmd.Flags().StringVar(&mockClientSecret, "client-secret", "", "client secret to accept, not base64 encoded")
+	mockServerCmd.Flags().StringArrayVar(&mockFaults, "fault", nil,
+		"fault to inject, as comma-separated settings: path=<part of the request path>, status=<HTTP status>,\n"+
+			"slow=<duration to spread the response body over>, truncate (send only half of the body), and\n"+
+			"times=<number of requests to affect> - can be repeated")
+	mockServerCmd.Flags().DurationVar(&mockExpiry, "expiry", 365*24*time.Hour,
+		"how long the licenses and certificates served are valid for")
+	rootCmd.AddCommand(mockServerCmd)
+}
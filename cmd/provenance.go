@@ -0,0 +1,75 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/provenance"
+	"github.com/spf13/cobra"
+)
+
+// provenanceCmd represents the provenance command
+var provenanceCmd = &cobra.Command{
+	Use: "provenance [directory]",
+	Short: "List the assets downloaded to the given directory with where they came from, most recently downloaded " +
+		"first - if directory not specified, list those in the directory given by -p, or the current directory",
+	Example: "viya4-orders-cli provenance\n" + "viya4-orders-cli provenance $HOME/sas -o json",
+	Aliases: []string{"prov"},
+	Args:    cobra.MaximumNArgs(1),
+	// The metadata of downloaded assets is read from disk, so there is no need to authenticate.
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		initConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := assetFilePath
+		if len(args) == 1 {
+			dir = args[0]
+		}
+		err := provenanceList(dir)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(provenanceCmd)
+}
+
+// provenanceList prints the assets in the given directory, or the current directory if none is given, that have
+// metadata files, in the output format given by the caller.
+func provenanceList(dir string) error {
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return errors.New("ERROR: os.Getwd() returned: " + err.Error())
+		}
+	}
+	assets, err := provenance.List(dir)
+	if err != nil {
+		return err
+	}
+
+	if !textOutput(outFormat) {
+		return assetreqs.Print(os.Stdout, outFormat, assets, assets)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tORDER\tASSET\tCADENCE\tRELEASE\tSIZE\tDOWNLOADED\tSHA256\tSTATUS")
+	for _, a := range assets {
+		digest := a.SHA256
+		if len(digest) > 12 {
+			digest = digest[:12]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", a.AssetFile, a.OrderNumber, a.AssetName, a.Cadence,
+			a.CadenceRelease, a.Size, a.DownloadedAt.Local().Format("2006-01-02 15:04:05"), digest, a.Status)
+	}
+	return tw.Flush()
+}
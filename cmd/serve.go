@@ -0,0 +1,114 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/authn"
+	"github.com/sassoftware/viya4-orders-cli/lib/cache"
+	"github.com/sassoftware/viya4-orders-cli/lib/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use: "serve",
+	Short: "Run an HTTP server that gets order assets for callers, so that they do not need the SAS Viya Orders API " +
+		"credentials",
+	Example: "viya4-orders-cli serve --listen :8080\n" +
+		"viya4-orders-cli serve --listen :8443 --tls-cert server.crt --tls-key server.key --client-ca clients-ca.crt --cache\n" +
+		"curl -OJ -H \"Authorization: Bearer $TOKEN\" 'http://localhost:8080/orders/993456/deploymentAssets?cadence=stable'",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if toStdout || uploadDest != "" {
+			usageError("serve cannot be used with --stdout or --upload!")
+		}
+
+		var c *cache.Cache
+		if useCache {
+			var err error
+			c, err = cache.New(cacheDir)
+			if err != nil {
+				fatal(err)
+			}
+		}
+		// Bearer tokens expire long before the server stops, so get a new one whenever it is needed.
+		var ts oauth2.TokenSource
+		if clientCredsType == "apigee" {
+			var err error
+			ts, err = authn.TokenSource(clientID, clientSecret)
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		srv, err := server.New(server.Config{
+			Addr:         viper.GetString("serve-listen"),
+			Tokens:       viper.GetStringSlice("serveTokens"),
+			CertFile:     viper.GetString("serve-tls-cert"),
+			KeyFile:      viper.GetString("serve-tls-key"),
+			ClientCAFile: viper.GetString("serve-client-ca"),
+			NewReq: func(assetName, orderNum, cadenceName, cadenceVer, cadenceRel string) (assetreqs.AssetReq, error) {
+				tok := token
+				if ts != nil {
+					t, err := ts.Token()
+					if err != nil {
+						return assetreqs.AssetReq{}, err
+					}
+					tok = t.AccessToken
+				}
+				ar := assetreqs.New(clientCredsType, tok, clientID, clientSecret, assetName, orderNum, cadenceName, cadenceVer, cadenceRel, "", "", outFormat, allowUnsuppd)
+				if c != nil {
+					ar = ar.WithCache(c)
+				}
+				if notifier != nil {
+					ar = ar.WithNotifier(notifier)
+				}
+				return ar, nil
+			},
+			Notifier: notifier,
+		})
+		if err != nil {
+			usageError(strings.TrimPrefix(err.Error(), "ERROR: "))
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		slog.Info("listening", "address", viper.GetString("serve-listen"))
+		err = srv.ListenAndServe(ctx)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("listen", ":8080", "address to listen on")
+	serveCmd.Flags().String("tls-cert", "", "certificate file to serve HTTPS with")
+	serveCmd.Flags().String("tls-key", "", "key file for the certificate given by --tls-cert")
+	serveCmd.Flags().String("client-ca", "",
+		"CA certificate file - callers with a client certificate signed by it are allowed in (mTLS)")
+	for key, flag := range map[string]string{
+		"serve-listen":    "listen",
+		"serve-tls-cert":  "tls-cert",
+		"serve-tls-key":   "tls-key",
+		"serve-client-ca": "client-ca",
+	} {
+		err := viper.BindPFlag(key, serveCmd.Flags().Lookup(flag))
+		if err != nil {
+			log.Fatalln("ERROR: viper.BindPFlag() returned: " + err.Error())
+		}
+	}
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,278 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/authn"
+	"github.com/sassoftware/viya4-orders-cli/lib/metrics"
+	"github.com/sassoftware/viya4-orders-cli/lib/provenance"
+	"github.com/sassoftware/viya4-orders-cli/lib/watch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+var watchOnce bool
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use: "watch [order number] [cadence name] [cadence version]",
+	Short: "Check periodically for a new release of the given cadence name and version, and download it and run " +
+		"actions when one appears - if version not specified, watch the latest version of the given cadence name",
+	Example: "viya4-orders-cli watch 993456 stable --interval 6h --hook 'kubectl apply -k $HOME/sas/site'\n" +
+		"viya4-orders-cli watch 993456 stable 2025.01 -p $HOME/sas --webhook https://hooks.example.com/viya\n" +
+		"viya4-orders-cli watch 993456 stable --once",
+	Args: cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		cver := ""
+		if len(args) == 3 {
+			cver = args[2]
+		}
+		if toStdout {
+			usageError("watch cannot be used with --stdout!")
+		}
+		interval := viper.GetDuration("watch-interval")
+		if interval <= 0 {
+			usageError("invalid value " + viper.GetString("watch-interval") + " specified for --interval option!")
+		}
+		stateFile := viper.GetString("watch-state-file")
+		if stateFile == "" {
+			var err error
+			stateFile, err = watch.DefaultStateFile(args[0], args[1], cver)
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		// Bearer tokens expire long before a watch stops, so get a new one whenever it is needed.
+		var ts oauth2.TokenSource
+		if clientCredsType == "apigee" && !dryRun {
+			var err error
+			ts, err = authn.TokenSource(clientID, clientSecret)
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		metricsErr := make(chan error, 1)
+		if addr := viper.GetString("watch-metrics-listen"); addr != "" && !watchOnce {
+			// Report the release seen before this watch started until the first check is done.
+			state, err := watch.LoadState(stateFile)
+			if err != nil {
+				fatal(err)
+			}
+			if state.CadenceRelease != "" {
+				metrics.SetLatestRelease(args[0], strings.ToLower(args[1]), strings.ToLower(cver), state.Cadence,
+					state.CadenceRelease)
+			}
+			go func() {
+				if err := metrics.ListenAndServe(ctx, addr); err != nil {
+					metricsErr <- err
+				}
+			}()
+			slog.Info("serving metrics", "address", addr, "path", "/metrics")
+		}
+		err := watchLoop(ctx, interval, watchOnce, func() error {
+			return watchCheck(ts, args[0], args[1], cver, stateFile)
+		}, metricsErr)
+		if err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	watchCmd.Flags().Duration("interval", 6*time.Hour, "how often to check for a new release (for example: 30m, 6h)")
+	watchCmd.Flags().String("state-file", "",
+		"file where the last release seen is kept (default is <order number>_<cadence name>[_<cadence version>].json\n"+
+			"in the viya4-orders-cli/watch directory of your user config directory)")
+	watchCmd.Flags().String("hook", "",
+		"shell command to run when a new release has been downloaded - it gets the details in VIYA4_ORDER_NUMBER,\n"+
+			"VIYA4_CADENCE, VIYA4_CADENCE_RELEASE, VIYA4_PREVIOUS_RELEASE and VIYA4_ASSET_LOCATION, and as JSON on STDIN")
+	watchCmd.Flags().String("webhook", "", "URL to POST the details of a new release to, as JSON, when it has been downloaded")
+	watchCmd.Flags().String("metrics-listen", "", "address to serve Prometheus metrics at /metrics on (for example: :9090)")
+	watchCmd.Flags().Bool("act-on-first", false,
+		"treat the release found when no release has been seen yet as new: run the hook and webhook, and send\n"+
+			"notifications for it (by default it is only recorded)")
+	for key, flag := range map[string]string{
+		"watch-interval":       "interval",
+		"watch-state-file":     "state-file",
+		"watch-hook":           "hook",
+		"watch-webhook":        "webhook",
+		"watch-metrics-listen": "metrics-listen",
+		"watch-act-on-first":   "act-on-first",
+	} {
+		err := viper.BindPFlag(key, watchCmd.Flags().Lookup(flag))
+		if err != nil {
+			log.Fatalln("ERROR: viper.BindPFlag() returned: " + err.Error())
+		}
+	}
+	watchCmd.Flags().BoolVar(&watchOnce, "once", false, "check once and exit instead of checking periodically")
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watchLoop runs the given check, and then, unless once is set, runs it again every interval until the given context
+// is done. A check that fails is logged, and the next check goes ahead, unless once is set, when its error is returned.
+// An error received from errs, such as that of the metrics server, stops the loop and is returned.
+func watchLoop(ctx context.Context, interval time.Duration, once bool, check func() error, errs <-chan error) error {
+	for {
+		err := check()
+		if once {
+			return err
+		}
+		if err != nil {
+			logError(err)
+		}
+
+		slog.Info("waiting for next check", "at", time.Now().Add(interval).Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			return err
+		case <-time.After(interval):
+		}
+	}
+}
+
+// watchCheck gets the latest deployment assets for the given order, cadence name and version, and compares their
+// release with the last one seen, as kept in the given state file. If the release is new, the assets are kept, the
+// actions are run, and the state file is updated. Otherwise the assets are thrown away, or, if those of the last
+// release seen are still kept, not downloaded past their release at all. The release found when none has been seen
+// yet is only recorded, unless watch-act-on-first is set. Notifications are sent for new releases and failures, not
+// for every check. Bearer tokens come from the given source, if there is one.
+func watchCheck(ts oauth2.TokenSource, orderNum, cadenceName, cadenceVer, stateFile string) error {
+	state, err := watch.LoadState(stateFile)
+	if err != nil {
+		return err
+	}
+	tok := token
+	if ts != nil {
+		t, err := ts.Token()
+		if err != nil {
+			return err
+		}
+		tok = t.AccessToken
+	}
+
+	// Download into a directory next to where the assets are kept, so that a new release can be moved into place.
+	fPath := assetFilePath
+	if fPath == "" {
+		fPath, err = os.Getwd()
+		if err != nil {
+			return errors.New("ERROR: os.Getwd() returned: " + err.Error())
+		}
+	}
+	tmp, err := os.MkdirTemp(fPath, ".viya4-orders-cli-watch-")
+	if err != nil {
+		return errors.New("ERROR: attempt to create temporary directory in " + fPath + " failed: " + err.Error())
+	}
+	defer os.RemoveAll(tmp)
+
+	ar := withGlobalOptions(assetreqs.New(clientCredsType, tok, clientID, clientSecret, "deploymentAssets", orderNum, cadenceName, cadenceVer, "", tmp, assetFileName, outFormat, allowUnsuppd))
+	ar = ar.WithNotifier(nil)
+	if _, err := os.Stat(state.AssetLocation); state.CadenceRelease != "" && err == nil {
+		ar = ar.WithSavedAssets(state.AssetLocation, state.CadenceRelease)
+	}
+	output, err := ar.Fetch()
+	if err != nil {
+		if notifier != nil {
+			notifier.AssetFetched(output, err)
+		}
+		return err
+	}
+	now := time.Now().UTC()
+	state.LastChecked = now
+
+	if output.Outcome == "unchanged" || output.CadenceRelease == state.CadenceRelease {
+		slog.Info("no new release", "order", orderNum, "cadence", output.Cadence, "release", output.CadenceRelease)
+		return watch.SaveState(stateFile, state)
+	}
+
+	// Keep the new release.
+	dest := filepath.Join(fPath, filepath.Base(output.AssetLocation))
+	err = os.Rename(output.AssetLocation, dest)
+	if err != nil {
+		return errors.New("ERROR: attempt to move " + output.AssetLocation + " to " + dest + " failed: " + err.Error())
+	}
+	err = provenance.Move(output.AssetLocation, dest)
+	if err != nil {
+		return err
+	}
+	output.AssetLocation = dest
+	newState := watch.State{
+		OrderNumber:    orderNum,
+		CadenceName:    cadenceName,
+		CadenceVersion: cadenceVer,
+		Cadence:        output.Cadence,
+		CadenceRelease: output.CadenceRelease,
+		AssetLocation:  output.AssetLocation,
+		LastChecked:    now,
+		LastChanged:    now,
+	}
+	if state.CadenceRelease == "" && !viper.GetBool("watch-act-on-first") {
+		// The release that is out when a watch starts is not new to whoever started it, so it is only recorded.
+		slog.Info("first check - recorded the latest release", "order", orderNum, "cadence", output.Cadence,
+			"release", output.CadenceRelease)
+		err = ar.PrintOutput(output)
+		if err != nil {
+			return err
+		}
+		return watch.SaveState(stateFile, newState)
+	}
+
+	if state.CadenceRelease == "" {
+		slog.Info("first check - found the latest release", "order", orderNum, "cadence", output.Cadence,
+			"release", output.CadenceRelease)
+	} else {
+		slog.Info("new release", "order", orderNum, "cadence", output.Cadence, "release", output.CadenceRelease,
+			"previous", state.CadenceRelease)
+	}
+	err = ar.PrintOutput(output)
+	if err != nil {
+		return err
+	}
+	if notifier != nil {
+		notifier.AssetFetched(output, nil)
+		notifier.NewRelease(output, state.CadenceRelease)
+	}
+
+	// The state is only updated once the actions have succeeded, so that they are tried again at the next check.
+	e := watch.Event{
+		Event:           "newRelease",
+		OrderNumber:     output.OrderNumber,
+		Cadence:         output.Cadence,
+		CadenceRelease:  output.CadenceRelease,
+		PreviousRelease: state.CadenceRelease,
+		AssetLocation:   output.AssetLocation,
+	}
+	if hook := viper.GetString("watch-hook"); hook != "" {
+		err = watch.RunHook(hook, e)
+		if err != nil {
+			return err
+		}
+	}
+	if url := viper.GetString("watch-webhook"); url != "" {
+		err = watch.PostWebhook(url, e)
+		if err != nil {
+			return err
+		}
+	}
+
+	return watch.SaveState(stateFile, newState)
+}
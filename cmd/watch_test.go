@@ -0,0 +1,205 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/notify"
+	"github.com/sassoftware/viya4-orders-cli/lib/orderstest"
+	"github.com/sassoftware/viya4-orders-cli/lib/watch"
+	"github.com/spf13/viper"
+)
+
+func TestWatchLoop(t *testing.T) {
+	// With once set, the error of the only check is returned.
+	n := 0
+	checkErr := errors.New("ERROR: check failed")
+	err := watchLoop(context.Background(), time.Hour, true, func() error { n++; return checkErr }, nil)
+	if err != checkErr || n != 1 {
+		t.Errorf("watchLoop with once set returned %v after %d checks", err, n)
+	}
+
+	// Otherwise checks that fail do not stop the loop, which runs until the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	n = 0
+	err = watchLoop(ctx, time.Millisecond, false, func() error {
+		n++
+		if n == 3 {
+			cancel()
+		}
+		return checkErr
+	}, nil)
+	if err != nil || n != 3 {
+		t.Errorf("watchLoop returned %v after %d checks, want nil after 3", err, n)
+	}
+
+	// An error from the metrics server stops the loop without waiting for the next check.
+	errs := make(chan error, 1)
+	serveErr := errors.New("ERROR: listen tcp :9090: bind: address already in use")
+	errs <- serveErr
+	n = 0
+	err = watchLoop(context.Background(), time.Hour, false, func() error { n++; return nil }, errs)
+	if err != serveErr || n != 1 {
+		t.Errorf("watchLoop returned %v after %d checks, want %v after 1", err, n, serveErr)
+	}
+}
+
+// watchTest sets up the global options for a watch of order 923457 against a mock of the API, with a hook that
+// appends its releases to a file and a notifier that records the events that it is sent.
+type watchTest struct {
+	api       *orderstest.Server
+	dir       string
+	hookOut   string
+	stateFile string
+
+	mu     sync.Mutex
+	events []string
+}
+
+func newWatchTest(t *testing.T) *watchTest {
+	t.Helper()
+	api, err := orderstest.NewServer(orderstest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(api.Close)
+	assetreqs.SetAPIHost(api.URL)
+	t.Cleanup(func() { assetreqs.SetAPIHost("") })
+
+	wt := &watchTest{api: api, dir: t.TempDir()}
+	wt.hookOut = filepath.Join(wt.dir, "hook.out")
+	wt.stateFile = filepath.Join(wt.dir, "watch", "923457_stable.json")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notify.Event
+		b, _ := io.ReadAll(r.Body)
+		if json.Unmarshal(b, &e) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		wt.mu.Lock()
+		wt.events = append(wt.events, e.Event)
+		wt.mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+	n, err := notify.New([]notify.Target{{URL: srv.URL}}, 30, apiclient.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := []string{clientCredsType, clientID, clientSecret, assetFilePath, outFormat}
+	savedNotifier := notifier
+	t.Cleanup(func() {
+		clientCredsType, clientID, clientSecret, assetFilePath, outFormat = saved[0], saved[1], saved[2], saved[3],
+			saved[4]
+		notifier = savedNotifier
+		viper.Set("watch-hook", "")
+		viper.Set("watch-act-on-first", false)
+	})
+	clientCredsType, clientID, clientSecret, assetFilePath, outFormat = "apim", "id", "secret", wt.dir, "json"
+	notifier = n
+	viper.Set("watch-hook", `echo "$VIYA4_CADENCE_RELEASE $VIYA4_PREVIOUS_RELEASE" >> `+wt.hookOut)
+	return wt
+}
+
+// check runs a check, and returns the releases that the hook was run for, the events that were sent, and its error.
+func (wt *watchTest) check(t *testing.T) (hooked string, events []string, err error) {
+	t.Helper()
+	_ = os.Remove(wt.hookOut)
+	wt.mu.Lock()
+	wt.events = nil
+	wt.mu.Unlock()
+
+	err = watchCheck(nil, "923457", "stable", "", wt.stateFile)
+	b, _ := os.ReadFile(wt.hookOut)
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+	return strings.TrimSpace(string(b)), slices.Clone(wt.events), err
+}
+
+func (wt *watchTest) state(t *testing.T) watch.State {
+	t.Helper()
+	s, err := watch.LoadState(wt.stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWatchCheck(t *testing.T) {
+	wt := newWatchTest(t)
+	const first, second = "20260215.1771111111111", "20260301.1772323200000"
+
+	// The release that is out when the watch starts is recorded, and nothing else happens.
+	hooked, events, err := wt.check(t)
+	if err != nil || hooked != "" || len(events) != 0 {
+		t.Fatalf("first check returned %v, ran the hook for %q, and sent %v", err, hooked, events)
+	}
+	s := wt.state(t)
+	if s.CadenceRelease != first || filepath.Dir(s.AssetLocation) != wt.dir {
+		t.Errorf("first check recorded %s at %s", s.CadenceRelease, s.AssetLocation)
+	}
+	if _, err = os.Stat(s.AssetLocation); err != nil {
+		t.Errorf("first check did not keep the assets: %v", err)
+	}
+
+	// A check that finds the same release does nothing either.
+	if hooked, events, err = wt.check(t); err != nil || hooked != "" || len(events) != 0 {
+		t.Errorf("check without a new release returned %v, ran the hook for %q, and sent %v", err, hooked, events)
+	}
+	if got := wt.state(t); got.CadenceRelease != first || !got.LastChecked.After(s.LastChecked) ||
+		!got.LastChanged.Equal(s.LastChanged) {
+		t.Errorf("check without a new release left the state %+v, from %+v", got, s)
+	}
+
+	// A new release runs the actions and is notified.
+	if err = wt.api.AddRelease("923457", "stable", "2026.01", second); err != nil {
+		t.Fatal(err)
+	}
+	hooked, events, err = wt.check(t)
+	if err != nil || hooked != second+" "+first ||
+		!slices.Equal(events, []string{notify.EventSuccess, notify.EventNewRelease}) {
+		t.Errorf("check with a new release returned %v, ran the hook for %q, and sent %v", err, hooked, events)
+	}
+	if s = wt.state(t); s.CadenceRelease != second {
+		t.Errorf("check with a new release recorded %s", s.CadenceRelease)
+	}
+
+	// A failed check is notified, and leaves the state as it was.
+	wt.api.InjectFault(orderstest.Fault{Status: http.StatusUnauthorized})
+	hooked, events, err = wt.check(t)
+	if err == nil || hooked != "" || !slices.Equal(events, []string{notify.EventFailure}) {
+		t.Errorf("failed check returned %v, ran the hook for %q, and sent %v", err, hooked, events)
+	}
+	if got := wt.state(t); got != s {
+		t.Errorf("failed check changed the state to %+v, from %+v", got, s)
+	}
+}
+
+func TestWatchCheckActOnFirst(t *testing.T) {
+	wt := newWatchTest(t)
+	viper.Set("watch-act-on-first", true)
+
+	hooked, events, err := wt.check(t)
+	if err != nil || hooked != "20260215.1771111111111" ||
+		!slices.Equal(events, []string{notify.EventSuccess, notify.EventNewRelease}) {
+		t.Errorf("first check with act-on-first set returned %v, ran the hook for %q, and sent %v", err, hooked,
+			events)
+	}
+}
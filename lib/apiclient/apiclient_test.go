@@ -0,0 +1,254 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package apiclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writePEM writes the given PEM blocks to a file in the given directory and returns its name.
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	t.Helper()
+	file := filepath.Join(dir, name)
+	var b []byte
+	for _, block := range blocks {
+		b = append(b, pem.EncodeToMemory(block)...)
+	}
+	if err := os.WriteFile(file, b, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+// writeCA writes the certificate of the given test server to a file in the given directory and returns its name.
+func writeCA(t *testing.T, dir string, srv *httptest.Server) string {
+	t.Helper()
+	return writePEM(t, dir, "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+// writeClientCert writes a new self-signed client certificate and its key to files in the given directory, and
+// returns their names and the certificate.
+func writeClientCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "viya4-orders-cli test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = writePEM(t, dir, "client.pem", &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyFile = writePEM(t, dir, "client-key.pem", &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certFile, keyFile, cert
+}
+
+// get sends a GET request for the given URL with the given transport and returns the body of the response.
+func get(tr http.RoundTripper, url string) (string, error) {
+	resp, err := (&http.Client{Transport: tr}).Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func TestNewTransportCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	tr, err := NewTransport(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = get(tr, srv.URL); err == nil {
+		t.Error("a server with a certificate from an unknown CA was trusted")
+	}
+
+	tr, err = NewTransport(Config{CAFile: writeCA(t, dir, srv)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := get(tr, srv.URL); err != nil || body != "ok" {
+		t.Errorf("request to a server with a certificate from the given CA returned %q, %v", body, err)
+	}
+
+	if _, err = NewTransport(Config{CAFile: filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("NewTransport accepted a CA file that does not exist")
+	}
+	empty := writePEM(t, dir, "empty.pem")
+	if _, err = NewTransport(Config{CAFile: empty}); err == nil {
+		t.Error("NewTransport accepted a CA file without certificates")
+	}
+}
+
+func TestNewTransportClientCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, cert := writeClientCert(t, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := writeCA(t, dir, srv)
+
+	tr, err := NewTransport(Config{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = get(tr, srv.URL); err == nil {
+		t.Error("a server that requires a client certificate accepted a request without one")
+	}
+
+	tr, err = NewTransport(Config{CAFile: caFile, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := get(tr, srv.URL); err != nil || body != cert.Subject.CommonName {
+		t.Errorf("request with a client certificate returned %q, %v", body, err)
+	}
+
+	if _, err = NewTransport(Config{CertFile: certFile}); err == nil {
+		t.Error("NewTransport accepted a client certificate without a key")
+	}
+	if _, err = NewTransport(Config{CertFile: certFile, KeyFile: caFile}); err == nil {
+		t.Error("NewTransport accepted a client certificate with a file that holds no key")
+	}
+}
+
+func TestNewTransportProxy(t *testing.T) {
+	// The proxy answers requests itself, recording the hosts that they were for.
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.Host)
+		mu.Unlock()
+		_, _ = io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	tr, err := NewTransport(Config{Proxy: proxy.URL, NoProxy: "direct.test,.internal.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := get(tr, "http://api.orders.test/mysas/orders"); err != nil || body != "proxied" {
+		t.Errorf("request through the proxy returned %q, %v", body, err)
+	}
+	// Hosts in NoProxy are connected to directly. They do not exist, so the requests fail.
+	for _, url := range []string{"http://direct.test/mysas/orders", "http://api.internal.test/mysas/orders"} {
+		if _, err = get(tr, url); err == nil {
+			t.Errorf("request for %s went through the proxy", url)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(proxied) != 1 || proxied[0] != "api.orders.test" {
+		t.Errorf("the proxy was sent requests for %v, want [api.orders.test]", proxied)
+	}
+
+	// NO_PROXY is used when no NoProxy is given.
+	t.Setenv("NO_PROXY", "direct.test")
+	t.Setenv("no_proxy", "direct.test")
+	tr, err = NewTransport(Config{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for url, want := range map[string]bool{"https://direct.test/": false, "https://api.orders.test/": true} {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		u, err := tr.Proxy(req)
+		if err != nil || (u != nil) != want {
+			t.Errorf("the proxy for %s is %v (%v), want proxied %t", url, u, err, want)
+		}
+	}
+
+	for _, p := range []string{"ftp://proxy.example.com", "proxy.example.com:3128"} {
+		if _, err = NewTransport(Config{Proxy: p}); err == nil {
+			t.Errorf("NewTransport accepted the proxy URL %s", p)
+		}
+	}
+}
+
+func TestNewTransportNoProxyWithEnvironmentProxy(t *testing.T) {
+	var mu sync.Mutex
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		proxied = append(proxied, r.URL.Host)
+		mu.Unlock()
+		_, _ = io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+	for _, v := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy"} {
+		t.Setenv(v, proxy.URL)
+	}
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+
+	// The proxy comes from the environment, and the hosts to connect to directly from the Config.
+	tr, err := NewTransport(Config{NoProxy: "direct.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, err := get(tr, "http://api.orders.test/mysas/orders"); err != nil || body != "proxied" {
+		t.Errorf("request through the proxy from the environment returned %q, %v", body, err)
+	}
+	if _, err = get(tr, "http://direct.test/mysas/orders"); err == nil {
+		t.Error("request for a host in NoProxy went through the proxy from the environment")
+	}
+	for url, want := range map[string]bool{"https://direct.test/": false, "https://api.orders.test/": true} {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		u, err := tr.Proxy(req)
+		if err != nil || (u != nil) != want {
+			t.Errorf("the proxy for %s is %v (%v), want proxied %t", url, u, err, want)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(proxied) != 1 || proxied[0] != "api.orders.test" {
+		t.Errorf("the proxy was sent requests for %v, want [api.orders.test]", proxied)
+	}
+}
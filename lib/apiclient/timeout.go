@@ -0,0 +1,198 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package apiclient
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// The parts of a request that can time out.
+const (
+	TimeoutConnect = "connect"
+	TimeoutTLS     = "tls"
+	TimeoutHeader  = "header"
+	TimeoutStall   = "stall"
+	TimeoutTotal   = "total"
+)
+
+// TimeoutError is a request that took longer than it was allowed to.
+type TimeoutError struct {
+	Kind  string        // which part of the request timed out: one of the Timeout constants
+	Limit time.Duration // how long that part was allowed to take
+}
+
+func (e *TimeoutError) Error() string {
+	limit := e.Limit.String()
+	switch e.Kind {
+	case TimeoutConnect:
+		return "the connection to the server was not made within " + limit
+	case TimeoutTLS:
+		return "the TLS handshake with the server did not finish within " + limit
+	case TimeoutHeader:
+		return "the server did not start to respond within " + limit + " of the request being sent"
+	case TimeoutStall:
+		return "the download stalled: no data was received for " + limit
+	default:
+		return "the request did not finish within " + limit
+	}
+}
+
+// Timeout returns true, so that a TimeoutError is a net.Error that timed out.
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary returns true, since the request may succeed if it is tried again.
+func (e *TimeoutError) Temporary() bool {
+	return true
+}
+
+// timeoutTransport enforces the timeouts that are not enforced by the transport that it wraps: how long the body of a
+// response can go without data, and how long a whole request can take. It also tells which part of a request timed
+// out.
+type timeoutTransport struct {
+	next http.RoundTripper
+	cfg  Config
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	if t.cfg.Timeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithTimeoutCause(ctx, t.cfg.Timeout,
+			&TimeoutError{Kind: TimeoutTotal, Limit: t.cfg.Timeout})
+		parent := cancel
+		cancel = func(cause error) { parent(cause); stop() }
+	}
+	ph := &phases{}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, ph.clientTrace()))
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		err = t.timeoutError(ctx, ph, err)
+		cancel(nil)
+		return nil, err
+	}
+	resp.Body = newStallBody(ctx, cancel, resp.Body, t.cfg.StallTimeout)
+	return resp, nil
+}
+
+// timeoutError returns the TimeoutError for the given error if it is a timeout, or the error as it is otherwise.
+func (t *timeoutTransport) timeoutError(ctx context.Context, ph *phases, err error) error {
+	var te *TimeoutError
+	if errors.As(context.Cause(ctx), &te) {
+		return te
+	}
+	var ne net.Error
+	if !errors.As(err, &ne) || !ne.Timeout() {
+		return err
+	}
+	switch ph.current() {
+	case TimeoutConnect:
+		return &TimeoutError{Kind: TimeoutConnect, Limit: t.cfg.ConnectTimeout}
+	case TimeoutTLS:
+		return &TimeoutError{Kind: TimeoutTLS, Limit: t.cfg.TLSTimeout}
+	case TimeoutHeader:
+		return &TimeoutError{Kind: TimeoutHeader, Limit: t.cfg.HeaderTimeout}
+	}
+	return err
+}
+
+// phases keeps track of which part of a request is in progress.
+type phases struct {
+	mu                             sync.Mutex
+	connecting, handshaking, ready bool
+}
+
+// clientTrace returns the hooks that keep track of the parts of a request.
+func (ph *phases) clientTrace() *httptrace.ClientTrace {
+	set := func(f func()) {
+		ph.mu.Lock()
+		defer ph.mu.Unlock()
+		f()
+	}
+	return &httptrace.ClientTrace{
+		ConnectStart: func(string, string) { set(func() { ph.connecting = true }) },
+		// A part that failed is left in progress, so that a timeout is put down to it.
+		ConnectDone:       func(_, _ string, err error) { set(func() { ph.connecting = ph.connecting && err != nil }) },
+		TLSHandshakeStart: func() { set(func() { ph.handshaking = true }) },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			set(func() { ph.handshaking = ph.handshaking && err != nil })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) { set(func() { ph.ready = true }) },
+	}
+}
+
+// current returns the part of the request that is in progress: one of the Timeout constants, or an empty string if it
+// is not known.
+func (ph *phases) current() string {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	switch {
+	case ph.handshaking:
+		return TimeoutTLS
+	case ph.connecting:
+		return TimeoutConnect
+	case ph.ready:
+		return TimeoutHeader
+	}
+	return ""
+}
+
+// stallBody is the body of a response that is abandoned if no data arrives for too long. Only the time spent waiting
+// for data counts: the time that the reader takes with the data between reads, for example to write it somewhere slow,
+// does not.
+type stallBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	limit  time.Duration
+	timer  *time.Timer
+}
+
+// newStallBody returns the given body, which is read under the given context, as a stallBody that cancels the context
+// if a read waits longer than the given limit for data (never if it is 0).
+func newStallBody(ctx context.Context, cancel context.CancelCauseFunc, body io.ReadCloser,
+	limit time.Duration) *stallBody {
+	b := &stallBody{ReadCloser: body, ctx: ctx, cancel: cancel, limit: limit}
+	if limit > 0 {
+		b.timer = time.AfterFunc(limit, func() { cancel(&TimeoutError{Kind: TimeoutStall, Limit: limit}) })
+		b.timer.Stop()
+	}
+	return b
+}
+
+func (b *stallBody) Read(p []byte) (int, error) {
+	if b.timer != nil {
+		b.timer.Reset(b.limit)
+	}
+	n, err := b.ReadCloser.Read(p)
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	if err != nil && err != io.EOF {
+		var te *TimeoutError
+		if errors.As(context.Cause(b.ctx), &te) {
+			err = te
+		}
+	}
+	return n, err
+}
+
+func (b *stallBody) Close() error {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	err := b.ReadCloser.Close()
+	b.cancel(nil)
+	return err
+}
@@ -0,0 +1,64 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package apiclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStallTimeout(t *testing.T) {
+	// The body is sent in pieces, with the given pause before the last one.
+	pause := make(chan time.Duration, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := <-pause
+		for i := 0; i < 4; i++ {
+			if i == 3 {
+				time.Sleep(d)
+			}
+			_, _ = io.WriteString(w, strings.Repeat("x", 1024))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: &timeoutTransport{next: http.DefaultTransport,
+		cfg: Config{StallTimeout: 200 * time.Millisecond}}}
+
+	// read reads the body of a response, taking the given time over each piece of it.
+	read := func(serverPause, readerPause time.Duration) error {
+		pause <- serverPause
+		resp, err := c.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		p := make([]byte, 1024)
+		for {
+			time.Sleep(readerPause)
+			_, err = resp.Body.Read(p)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// A reader that takes its time with the data does not make the download stall.
+	if err := read(0, 300*time.Millisecond); err != nil {
+		t.Errorf("a slow reader made the download fail: %v", err)
+	}
+	// No data arriving does.
+	err := read(time.Second, 0)
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Kind != TimeoutStall {
+		t.Errorf("a stalled download returned %v, want a stall TimeoutError", err)
+	}
+}
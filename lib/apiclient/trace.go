@@ -0,0 +1,189 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package apiclient
+
+import (
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redacted replaces the values, such as credentials, that are never logged or printed.
+const Redacted string = "[REDACTED]"
+
+// The headers that are logged, if present. Those that carry credentials are logged as Redacted.
+var (
+	requestHeaders  = []string{"Accept", "Content-Type", "Content-Length", "If-None-Match", "If-Modified-Since"}
+	responseHeaders = []string{"Content-Type", "Content-Length", "Content-Disposition", "ETag", "Last-Modified",
+		"Retry-After", "Location", "WWW-Authenticate", "X-Request-Id", "X-Correlation-Id"}
+	secretHeaders = []string{"Authorization", "ClientId", "ClientSecret", "Cookie", "Set-Cookie"}
+)
+
+// tracingTransport logs every request that it sends and the response to it.
+type tracingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tm := &timings{start: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), tm.clientTrace()))
+
+	attrs := []any{"method", req.Method, "url", req.URL.Redacted(), headerGroup(req.Header, requestHeaders)}
+	if req.Body != nil && req.Body != http.NoBody {
+		// Request bodies, such as those of Bearer token requests, can hold the client credentials.
+		attrs = append(attrs, "body", Redacted)
+	}
+	slog.Info("http request", attrs...)
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		slog.Info("http request failed", "method", req.Method, "url", req.URL.Redacted(), "error", err.Error(),
+			tm.group())
+		return nil, err
+	}
+	// The response is logged once its body has been read, so that its size is known.
+	resp.Body = &tracedBody{ReadCloser: resp.Body, req: req, resp: resp, tm: tm}
+	return resp, nil
+}
+
+// headerGroup returns a log attribute holding the values of the given headers and of the headers that carry
+// credentials, which are Redacted.
+func headerGroup(h http.Header, names []string) slog.Attr {
+	var attrs []any
+	for _, name := range names {
+		if v := strings.Join(headerValues(h, name), ", "); v != "" {
+			attrs = append(attrs, name, v)
+		}
+	}
+	for _, name := range secretHeaders {
+		vs := headerValues(h, name)
+		if len(vs) == 0 {
+			continue
+		}
+		v := Redacted
+		// The kind of authorization is useful to know, and is not a secret.
+		if scheme, _, ok := strings.Cut(vs[0], " "); ok && name == "Authorization" {
+			v = scheme + " " + Redacted
+		}
+		attrs = append(attrs, name, v)
+	}
+	return slog.Group("headers", attrs...)
+}
+
+// headerValues returns the values of the named header, whether or not its name was set in canonical form. The
+// ClientId and ClientSecret headers are set as they are named, since the API expects them that way.
+func headerValues(h http.Header, name string) []string {
+	if vs := h.Values(name); len(vs) > 0 {
+		return vs
+	}
+	return h[name]
+}
+
+// timings records how long each part of an exchange with the server took.
+type timings struct {
+	mu                  sync.Mutex
+	start               time.Time
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	firstByte           time.Time
+	reused              bool
+}
+
+// clientTrace returns the hooks that record the timings of a request.
+func (tm *timings) clientTrace() *httptrace.ClientTrace {
+	set := func(t *time.Time) {
+		tm.mu.Lock()
+		defer tm.mu.Unlock()
+		*t = time.Now()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { set(&tm.dnsStart) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { set(&tm.dnsDone) },
+		ConnectStart: func(string, string) {
+			tm.mu.Lock()
+			defer tm.mu.Unlock()
+			// Connections to several addresses can be attempted at once; time from the first attempt.
+			if tm.connStart.IsZero() {
+				tm.connStart = time.Now()
+			}
+		},
+		ConnectDone:          func(string, string, error) { set(&tm.connDone) },
+		TLSHandshakeStart:    func() { set(&tm.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { set(&tm.tlsDone) },
+		GotFirstResponseByte: func() { set(&tm.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			tm.mu.Lock()
+			defer tm.mu.Unlock()
+			tm.reused = info.Reused
+		},
+	}
+}
+
+// group returns a log attribute holding the timings recorded so far.
+func (tm *timings) group() slog.Attr {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	var attrs []any
+	since := func(name string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			attrs = append(attrs, name, to.Sub(from).Round(time.Microsecond))
+		}
+	}
+	since("dns", tm.dnsStart, tm.dnsDone)
+	since("connect", tm.connStart, tm.connDone)
+	since("tls", tm.tlsStart, tm.tlsDone)
+	since("firstByte", tm.start, tm.firstByte)
+	if tm.reused {
+		attrs = append(attrs, "reusedConnection", true)
+	}
+	attrs = append(attrs, "total", time.Since(tm.start).Round(time.Microsecond))
+	return slog.Group("timing", attrs...)
+}
+
+// tracedBody is the body of a response that is logged once the body has been read to its end or closed.
+type tracedBody struct {
+	io.ReadCloser
+	req    *http.Request
+	resp   *http.Response
+	tm     *timings
+	size   int64
+	err    error
+	logged bool
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	if err == io.EOF {
+		b.log()
+	} else if err != nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	b.log()
+	return b.ReadCloser.Close()
+}
+
+// log logs the response, if it has not been logged yet.
+func (b *tracedBody) log() {
+	if b.logged {
+		return
+	}
+	b.logged = true
+	attrs := []any{"method", b.req.Method, "url", b.req.URL.Redacted(), "status", b.resp.StatusCode,
+		headerGroup(b.resp.Header, responseHeaders), "size", b.size}
+	if b.err != nil {
+		attrs = append(attrs, "error", b.err.Error())
+	}
+	slog.Info("http response", append(attrs, b.tm.group())...)
+}
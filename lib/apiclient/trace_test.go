@@ -0,0 +1,105 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package apiclient
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// captureLog sends what is logged to a buffer until the end of the test, and turns tracing on.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	SetTrace(true)
+	t.Cleanup(func() {
+		SetTrace(false)
+		slog.SetDefault(old)
+	})
+	return &buf
+}
+
+func TestTraceRedactsCredentials(t *testing.T) {
+	const (
+		clientID     = "c2VjcmV0LWlk"
+		clientSecret = "c2VjcmV0LXNlY3JldA"
+		accessToken  = "eyJhY2Nlc3MtdG9rZW4"
+		cookie       = "session=c2Vzc2lvbg"
+		password     = "cHJveHktcGFzc3dvcmQ"
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "c2Vzc2lvbg"})
+		if r.URL.Path == "/mysas/token" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"access_token":"`+accessToken+`","token_type":"bearer","expires_in":1800}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/zip")
+		_, _ = io.WriteString(w, "certificates")
+	}))
+	defer srv.Close()
+	buf := captureLog(t)
+
+	send := func(req *http.Request) {
+		t.Helper()
+		resp, err := Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// A Bearer token request, as sent for Apigee credentials: the credentials are in the body.
+	form := url.Values{"grant_type": {"client_credentials"}, "client_id": {clientID}, "client_secret": {clientSecret}}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/mysas/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+	send(req)
+
+	// An asset request, as sent for APIM credentials: the credentials are in headers, set as the API names them.
+	u, _ := url.Parse(srv.URL + "/mysas/orders/923457/certificates")
+	u.User = url.UserPassword("user", password)
+	req, _ = http.NewRequest(http.MethodGet, u.String(), nil)
+	req.Header["ClientId"] = []string{clientID}
+	req.Header["ClientSecret"] = []string{clientSecret}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Cookie", cookie)
+	send(req)
+
+	logged := buf.String()
+	for _, secret := range []string{clientID, clientSecret, accessToken, "c2Vzc2lvbg", password,
+		"grant_type", "client_credentials"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("the trace holds %s:\n%s", secret, logged)
+		}
+	}
+	// What was sent is still there, with the credentials redacted.
+	for _, want := range []string{
+		"headers.Authorization=\"Basic " + Redacted + "\"",
+		"headers.Authorization=\"Bearer " + Redacted + "\"",
+		"headers.ClientId=" + Redacted,
+		"headers.ClientSecret=" + Redacted,
+		"headers.Cookie=" + Redacted,
+		"headers.Set-Cookie=" + Redacted,
+		"body=" + Redacted,
+		"url=http://user:xxxxx@",
+		"headers.Content-Type=application/zip",
+	} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("the trace does not hold %s:\n%s", want, logged)
+		}
+	}
+	if n := strings.Count(logged, "msg=\"http response\""); n != 2 {
+		t.Errorf("the trace holds %d responses, want 2:\n%s", n, logged)
+	}
+}
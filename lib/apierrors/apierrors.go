@@ -0,0 +1,180 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package apierrors provides the errors returned for failed SAS Viya Orders API requests, classified so that callers
+// can tell, for example, bad credentials apart from a cadence that is out of support or a request to retry later.
+package apierrors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The kinds of failure. Use errors.Is to check whether an error is of one of these kinds.
+var (
+	ErrUnauthorized       = errors.New("the credentials were not accepted")
+	ErrOrderNotFound      = errors.New("the order was not found")
+	ErrNotFound           = errors.New("the order or cadence was not found") // when it is not known which is missing
+	ErrCadenceUnsupported = errors.New("the cadence is no longer supported")
+	ErrRateLimited        = errors.New("too many requests")
+	ErrServer             = errors.New("the API failed")
+	ErrNetwork            = errors.New("the request failed to complete")
+)
+
+// Error is an error response from the SAS Viya Orders API, with the details from its body.
+type Error struct {
+	Op          string        `json:"-"` // what was requested, for example "asset request"
+	URL         string        `json:"requestURL,omitempty"`
+	StatusCode  int           `json:"httpStatusCode"`
+	ErrorCode   int           `json:"errorCode,omitempty"`
+	Message     string        `json:"message,omitempty"`
+	Details     []string      `json:"details,omitempty"`
+	Remediation string        `json:"remediation,omitempty"`
+	Body        string        `json:"body,omitempty"` // the body as sent, if it held no message
+	RetryAfter  time.Duration `json:"-"`              // how long to wait before trying again, if the API said
+	kind        error
+}
+
+// FromResponse returns an Error for the given response, which was not a success, to the given request. It reads the
+// body of the response, as much of it as can be read.
+func FromResponse(op string, resp *http.Response) *Error {
+	body, _ := io.ReadAll(resp.Body)
+	var u string
+	if resp.Request != nil {
+		u = resp.Request.URL.String()
+	}
+	return New(op, u, resp.StatusCode, resp.Header, body)
+}
+
+// New returns an Error for a response to the given request to the given URL with the given status, headers, and body.
+func New(op, url string, status int, header http.Header, body []byte) *Error {
+	e := &Error{Op: op, URL: url, StatusCode: status}
+
+	// The API gateways and the API each describe errors in their own way.
+	var b struct {
+		ErrorCode   int      `json:"errorCode"`
+		Message     string   `json:"message"`
+		Details     []string `json:"details"`
+		Remediation string   `json:"remediation"`
+		Error       string   `json:"error"`
+		ErrorDesc   string   `json:"error_description"`
+		Fault       struct {
+			FaultString string `json:"faultstring"`
+		} `json:"fault"`
+	}
+	if json.Unmarshal(body, &b) == nil {
+		e.ErrorCode, e.Message, e.Details, e.Remediation = b.ErrorCode, b.Message, b.Details, b.Remediation
+		switch {
+		case e.Message != "":
+		case b.Fault.FaultString != "":
+			e.Message = b.Fault.FaultString
+		case b.ErrorDesc != "":
+			e.Message = b.Error + ": " + b.ErrorDesc
+		case b.Error != "":
+			e.Message = b.Error
+		}
+	}
+	if e.Message == "" {
+		e.Body = strings.TrimSpace(string(body))
+	}
+
+	if ra := header.Get("Retry-After"); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(ra); err == nil {
+			e.RetryAfter = time.Until(t)
+		}
+	}
+
+	e.kind = classify(status, b.Error, url, e.Message+" "+e.Body)
+	return e
+}
+
+// classify returns the kind of failure that a response to a request to the given URL, with the given status, OAuth
+// error code, and message, is. What was not found is told by what was requested, rather than by the message: only the
+// order can be missing for an endpoint of the order itself, but the order, the cadence name, version, or release can
+// be missing for an endpoint of a cadence.
+func classify(status int, oauthErr, reqURL, message string) error {
+	m := strings.ToLower(message)
+	switch {
+	case status >= 400 && status < 500 && !strings.Contains(m, "grant") && (strings.Contains(m, "not supported") ||
+		strings.Contains(m, "no longer supported") || strings.Contains(m, "unsupported") ||
+		strings.Contains(m, "out of support")):
+		return ErrCadenceUnsupported
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrUnauthorized
+	case status == http.StatusBadRequest && (oauthErr == "invalid_client" || oauthErr == "unauthorized_client"):
+		return ErrUnauthorized
+	case status == http.StatusNotFound && orderEndpoint(reqURL):
+		return ErrOrderNotFound
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+// orderEndpoint returns whether the given URL is of an endpoint of an order itself, such as its certificates, rather
+// than of one of its cadences.
+func orderEndpoint(reqURL string) bool {
+	u, err := url.Parse(reqURL)
+	if err != nil {
+		return false
+	}
+	orderPath, ok := strings.CutPrefix(u.Path, "/mysas/orders/")
+	return ok && strings.Count(orderPath, "/") == 1 && !strings.Contains(orderPath, "cadenceNames")
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if len(e.Details) > 0 {
+		msg += " (" + strings.Join(e.Details, "; ") + ")"
+	}
+	if e.Remediation != "" {
+		msg += " - " + e.Remediation
+	}
+	if msg == "" {
+		msg = e.Body
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("%d -- %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return "ERROR: " + e.Op + " failed: " + msg
+}
+
+// Unwrap returns the kind of failure, if it is known.
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+// NetworkError is a request that failed to complete, because the API could not be reached or its response was cut
+// short.
+type NetworkError struct {
+	Op  string // what was requested, for example "asset request"
+	URL string
+	Err error
+}
+
+// Network returns a NetworkError for the given request to the given URL, which failed with the given error.
+func Network(op, url string, err error) *NetworkError {
+	return &NetworkError{Op: op, URL: url, Err: err}
+}
+
+func (e *NetworkError) Error() string {
+	return "ERROR: " + e.Op + " failed to complete: " + e.Err.Error()
+}
+
+// Unwrap returns ErrNetwork and the error that the request failed with.
+func (e *NetworkError) Unwrap() []error {
+	return []error{ErrNetwork, e.Err}
+}
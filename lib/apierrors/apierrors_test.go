@@ -0,0 +1,42 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package apierrors
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	const orders = "https://api.sas.com/mysas/orders/923457/"
+	for _, tc := range []struct {
+		url    string
+		status int
+		body   string
+		kind   error
+	}{
+		{orders + "certificates", 404, `{"message":"Order 923457 not found"}`, ErrOrderNotFound},
+		{orders + "assetHistory", 404, `{"message":"Resource not found"}`, ErrOrderNotFound},
+		// What was not found is not told by the message.
+		{orders + "cadenceNames/stable/deploymentAssets", 404, `{"message":"Order 923457 not found"}`, ErrNotFound},
+		{orders + "cadenceNames/stable/cadenceVersions/2026.01/license", 404, `{"message":"Not found"}`,
+			ErrNotFound},
+		{orders + "cadenceNames/stable/cadenceVersions/2026.01/cadenceReleases/1/deploymentAssets", 404, "",
+			ErrNotFound},
+		{"https://api.sas.com/mysas/token", 404, `{"message":"Resource not found"}`, ErrNotFound},
+		{orders + "certificates", 401, `{"message":"Invalid credentials"}`, ErrUnauthorized},
+		{"https://api.sas.com/mysas/token", 400, `{"error":"invalid_client"}`, ErrUnauthorized},
+		{orders + "cadenceNames/lts/cadenceVersions/2024.09/license", 400,
+			`{"message":"Cadence LTS 2024.09 is no longer supported"}`, ErrCadenceUnsupported},
+		{orders + "certificates", 429, "", ErrRateLimited},
+		{orders + "certificates", 503, "", ErrServer},
+		{orders + "certificates", 400, `{"message":"Bad request"}`, nil},
+	} {
+		e := New("asset request", tc.url, tc.status, http.Header{}, []byte(tc.body))
+		if kind := errors.Unwrap(e); kind != tc.kind {
+			t.Errorf("%d from %s (%s) is of kind %v, want %v", tc.status, tc.url, tc.body, kind, tc.kind)
+		}
+	}
+}
@@ -12,13 +12,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/apierrors"
+	"github.com/sassoftware/viya4-orders-cli/lib/cache"
+	"github.com/sassoftware/viya4-orders-cli/lib/metrics"
+	"github.com/sassoftware/viya4-orders-cli/lib/provenance"
 )
 
 // checksumsFile is where we can find cadence information within downloaded deployment assets.
@@ -31,6 +40,23 @@ const (
 	viyaOrdersAPIOrdersPath string = "/orders"
 )
 
+// apiHost is the SAS Viya Orders API host that requests go to instead of the usual ones, if set.
+var apiHost string
+
+// SetAPIHost makes asset requests go to the given SAS Viya Orders API host, such as a mock of the API, whatever the
+// type of client credentials.
+func SetAPIHost(host string) {
+	apiHost = host
+}
+
+// cliVersion is the version of SAS Viya Orders CLI, which is recorded in the metadata of the assets it saves.
+var cliVersion string
+
+// SetCLIVersion sets the version of SAS Viya Orders CLI that is recorded in the metadata of the assets it saves.
+func SetCLIVersion(v string) {
+	cliVersion = v
+}
+
 // AssetReq provides fields that define the parameters of an order asset request.
 type AssetReq struct {
 	clientCredsType string
@@ -46,6 +72,44 @@ type AssetReq struct {
 	fName           string
 	oFmt            string
 	allowUnsuppd    bool
+	histFilter      *HistoryFilter
+	dest            io.Writer
+	uploader        Uploader
+	cache           *cache.Cache
+	notifier        Notifier
+	progress        bool
+	dryRun          bool
+	ifChanged       bool
+	savedFile       string
+	savedRelease    string
+	noClobber       bool
+	sha512          bool
+	expectSHA256    string
+}
+
+// Uploader copies an asset to another destination, such as object storage, as it is downloaded.
+type Uploader interface {
+	// Begin starts uploading the asset with the given file name, size (-1 if not known), SHA-256 digest (empty if not
+	// known before the asset is read), and metadata, and returns a writer for its contents.
+	Begin(fileName string, size int64, digest string, meta map[string]string) (io.Writer, error)
+	// Commit finishes the upload that was begun. It returns the location of the uploaded asset, and whether an
+	// identical asset was already there and left as it is.
+	Commit() (location string, unchanged bool, err error)
+	// Abort abandons the upload that was begun.
+	Abort()
+}
+
+// AssetWriter is a writer that an asset can be streamed to which needs to know the file name and size (-1 if not
+// known) of the asset before its contents are written, such as an HTTP response.
+type AssetWriter interface {
+	io.Writer
+	BeginAsset(fileName string, size int64)
+}
+
+// Notifier is told about every asset request once it has succeeded or failed.
+type Notifier interface {
+	// AssetFetched is given the information about the requested asset, and the error if the request failed.
+	AssetFetched(output Output, err error)
 }
 
 // New initializes an AssetReq struct.
@@ -68,41 +132,88 @@ func New(credsType, token, cID, cSec, assetName, orderNum, cadenceName, cadenceV
 	}
 }
 
-var output out
+// WithHistoryFilter returns a copy of the AssetReq receiver that, for an assetHistory request, prints the downloads in
+// the asset history that match the given filter instead of information about the saved file.
+func (ar AssetReq) WithHistoryFilter(f HistoryFilter) AssetReq {
+	ar.histFilter = &f
+	return ar
+}
 
-// out defines the information that is printed to STDOUT.
-type out struct {
-	OrderNumber    string `json:"orderNumber"`
-	AssetName      string `json:"assetName"`
-	AssetReqURL    string `json:"assetReqURL"`
-	AssetLocation  string `json:"assetLocation"`
-	Cadence        string `json:"cadence"`
-	CadenceRelease string `json:"cadenceRelease"`
+// WithWriter returns a copy of the AssetReq receiver that streams the asset to the given writer instead of saving it to
+// a file. Information about the asset is then printed to STDERR, so that the asset can be streamed to STDOUT.
+func (ar AssetReq) WithWriter(w io.Writer) AssetReq {
+	ar.dest = w
+	return ar
+}
+
+// WithUploader returns a copy of the AssetReq receiver that also uploads the asset with the given Uploader as it is
+// downloaded.
+func (ar AssetReq) WithUploader(u Uploader) AssetReq {
+	ar.uploader = u
+	return ar
+}
+
+// WithCache returns a copy of the AssetReq receiver that satisfies the request from the given cache when the cached
+// asset is still current, and caches the asset otherwise.
+func (ar AssetReq) WithCache(c *cache.Cache) AssetReq {
+	ar.cache = c
+	return ar
+}
+
+// WithNotifier returns a copy of the AssetReq receiver that tells the given Notifier about the outcome of the request.
+func (ar AssetReq) WithNotifier(n Notifier) AssetReq {
+	ar.notifier = n
+	return ar
+}
+
+// WithProgress returns a copy of the AssetReq receiver that reports how the download of the asset is going on STDERR.
+func (ar AssetReq) WithProgress() AssetReq {
+	ar.progress = true
+	return ar
+}
+
+// Output defines the information about an order asset that is printed to STDOUT.
+type Output struct {
+	OrderNumber     string  `json:"orderNumber" yaml:"orderNumber"`
+	AssetName       string  `json:"assetName" yaml:"assetName"`
+	AssetReqURL     string  `json:"assetReqURL" yaml:"assetReqURL"`
+	AssetLocation   string  `json:"assetLocation" yaml:"assetLocation"`
+	Cadence         string  `json:"cadence" yaml:"cadence"`
+	CadenceRelease  string  `json:"cadenceRelease" yaml:"cadenceRelease"`
+	UploadLocation  string  `json:"uploadLocation,omitempty" yaml:"uploadLocation,omitempty"`
+	UploadStatus    string  `json:"uploadStatus,omitempty" yaml:"uploadStatus,omitempty"`
+	PushLocation    string  `json:"pushLocation,omitempty" yaml:"pushLocation,omitempty"`
+	PushDigest      string  `json:"pushDigest,omitempty" yaml:"pushDigest,omitempty"`
+	GitBranch       string  `json:"gitBranch,omitempty" yaml:"gitBranch,omitempty"`
+	GitCommit       string  `json:"gitCommit,omitempty" yaml:"gitCommit,omitempty"`
+	GitStatus       string  `json:"gitStatus,omitempty" yaml:"gitStatus,omitempty"`
+	CacheStatus     string  `json:"cacheStatus,omitempty" yaml:"cacheStatus,omitempty"`
+	AssetSize       int64   `json:"assetSize,omitempty" yaml:"assetSize,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty" yaml:"durationSeconds,omitempty"`
+	BytesPerSecond  int64   `json:"bytesPerSecond,omitempty" yaml:"bytesPerSecond,omitempty"`
+	Outcome         string  `json:"outcome,omitempty" yaml:"outcome,omitempty"`
+	SHA256          string  `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	SHA512          string  `json:"sha512,omitempty" yaml:"sha512,omitempty"`
 }
 
 // GetAsset fetches the requested order asset (as defined in the AssetReq receiver) from the SAS Viya Orders API and
 // prints information about it.
 func (ar AssetReq) GetAsset() error {
-	// Make the API call to download the requested asset
-	fileName, err := ar.makeReq()
+	if ar.dryRun {
+		return ar.printPlan()
+	}
+
+	output, err := ar.Fetch()
 	if err != nil {
 		return err
 	}
 
-	// Set the output struct properties.
-	// Cadence is only applicable to deploymentAssets and license.
-	if ar.aName == "deploymentAssets" || ar.aName == "license" {
-		output.Cadence, output.CadenceRelease, err = ar.getCadenceInfo(fileName)
-		if err != nil {
-			return err
-		}
+	if ar.aName == "assetHistory" && ar.histFilter != nil {
+		return ar.printHistory(output.AssetLocation)
 	}
-	output.OrderNumber = ar.oNum
-	output.AssetName = ar.aName
-	output.AssetLocation = fileName
 
 	// Print the output
-	err = ar.printOutput()
+	err = ar.PrintOutput(output)
 	if err != nil {
 		return err
 	}
@@ -110,18 +221,112 @@ func (ar AssetReq) GetAsset() error {
 	return nil
 }
 
-// getFileName determines the location where the asset will be saved on disk.
-func (ar AssetReq) getFileName(contentDisp string) (fileName string, err error) {
-	var filePath string
-	if ar.fPath != "" {
-		filePath = ar.fPath
-	} else {
-		filePath, err = os.Getwd()
+// Fetch fetches the requested order asset (as defined in the AssetReq receiver) from the SAS Viya Orders API and
+// returns information about it without printing it.
+func (ar AssetReq) Fetch() (output Output, err error) {
+	output.OrderNumber = ar.oNum
+	output.AssetName = ar.aName
+	start := time.Now()
+	defer func() { metrics.ObserveDownload(ar.aName, err, time.Since(start)) }()
+	if ar.notifier != nil {
+		defer func() { ar.notifier.AssetFetched(output, err) }()
+	}
+
+	// Make the API call to download the requested asset
+	fileName, err := ar.makeReq(&output)
+	if err != nil {
+		return output, err
+	}
+
+	// Set the output struct properties.
+	// Cadence is only applicable to deploymentAssets and license. Streamed deployment assets had their cadence
+	// information extracted as they passed through.
+	if ar.aName == "license" || (ar.aName == "deploymentAssets" && ar.dest == nil) {
+		output.Cadence, output.CadenceRelease, err = ar.getCadenceInfo(fileName)
 		if err != nil {
-			return fileName, errors.New("ERROR: os.Getwd() returned: " + err.Error())
+			if ar.uploader != nil {
+				ar.uploader.Abort()
+			}
+			return output, err
 		}
 	}
+	output.AssetLocation = fileName
+	if ar.aName == "deploymentAssets" && ar.cRel == "" {
+		metrics.SetLatestRelease(ar.oNum, strings.ToLower(ar.cName), strings.ToLower(ar.cVer), output.Cadence,
+			output.CadenceRelease)
+	}
 
+	// An asset that was saved before has already been recorded in the cache and uploaded, if it was meant to be.
+	if skipped(output) {
+		if ar.uploader != nil {
+			ar.uploader.Abort()
+		}
+		err = ar.digestFile(output.AssetLocation, &output)
+		if err != nil {
+			return output, err
+		}
+		err = ar.checkDigest(output)
+		if err != nil {
+			return output, err
+		}
+		slog.Info("kept saved asset", "order", ar.oNum, "asset", ar.aName, "location", output.AssetLocation,
+			"outcome", output.Outcome)
+		return output, nil
+	}
+
+	if output.CacheStatus != "" {
+		err = ar.cache.Record(output.AssetReqURL, output.Cadence, output.CadenceRelease)
+		if err != nil {
+			return output, err
+		}
+	}
+
+	// Now that the asset is known to be as expected, finish uploading it.
+	if ar.uploader != nil {
+		loc, unchanged, err := ar.uploader.Commit()
+		if err != nil {
+			return output, err
+		}
+		output.UploadLocation = loc
+		output.UploadStatus = "uploaded"
+		if unchanged {
+			output.UploadStatus = "unchanged"
+		}
+	}
+
+	// Record where the asset came from next to it, for as long as it is kept.
+	if ar.dest == nil {
+		err = ar.writeMetadata(output)
+		if err != nil {
+			return output, err
+		}
+	}
+
+	attrs := []any{"order", ar.oNum, "asset", ar.aName, "url", output.AssetReqURL, "location", output.AssetLocation}
+	if output.CadenceRelease != "" {
+		attrs = append(attrs, "release", output.CadenceRelease)
+	}
+	slog.Info("fetched asset", append(attrs, "duration", time.Since(start).Round(time.Millisecond))...)
+	return output, nil
+}
+
+// writeMetadata saves the metadata of the asset described by the given output struct next to the asset file.
+func (ar AssetReq) writeMetadata(output Output) error {
+	return provenance.Write(output.AssetLocation, provenance.Metadata{
+		OrderNumber:    output.OrderNumber,
+		AssetName:      output.AssetName,
+		AssetReqURL:    output.AssetReqURL,
+		Cadence:        output.Cadence,
+		CadenceRelease: output.CadenceRelease,
+		DownloadedAt:   time.Now().UTC(),
+		Size:           output.AssetSize,
+		SHA256:         output.SHA256,
+		CLIVersion:     cliVersion,
+	})
+}
+
+// getFileName determines the location where the asset will be saved on disk.
+func (ar AssetReq) getFileName(contentDisp string) (fileName string, err error) {
 	// Get the name of the asset file as returned by the API if applicable.
 	var apiFNm string
 	if ar.aName != "assetHistory" {
@@ -129,48 +334,64 @@ func (ar AssetReq) getFileName(contentDisp string) (fileName string, err error)
 		if err != nil {
 			return fileName, errors.New("ERROR: mime.ParseMediaType() returned: " + err.Error())
 		}
-		apiFNm = filepath.Join(filePath, params["filename"])
+		apiFNm = params["filename"]
 	} else {
 		apiFNm = ar.oNum + "_assetHistory.json"
 	}
 
-	if ar.fName != "" {
-		// Even if they specified -n, use the extension that the API returned
-		fileName = filepath.Join(filePath, ar.fName) + filepath.Ext(apiFNm)
-	} else {
-		fileName = apiFNm
+	return ar.filePath(apiFNm)
+}
+
+// PrintOutput prints the contents of the given output struct in the format specified by the caller.
+func (ar AssetReq) PrintOutput(output Output) (err error) {
+	oFmt := strings.ToLower(ar.oFmt)
+	if oFmt != "" && oFmt != "text" && oFmt != "t" {
+		return ar.print(&output)
 	}
 
-	return fileName, nil
+	s := reflect.ValueOf(&output).Elem()
+	typeOfT := s.Type()
+	for i := 0; i < s.NumField(); i++ {
+		f := s.Field(i)
+		// Leave out optional fields that were not set, as the JSON output does.
+		if f.IsZero() && strings.HasSuffix(typeOfT.Field(i).Tag.Get("json"), ",omitempty") {
+			continue
+		}
+		fmt.Fprintf(ar.infoWriter(), "%s: %v\n",
+			typeOfT.Field(i).Name, f.Interface())
+	}
+	return nil
 }
 
-// printOutput prints the contents of the output struct in the format specified by the caller.
-func (ar AssetReq) printOutput() (err error) {
-	if strings.ToLower(ar.oFmt) == "json" || strings.ToLower(ar.oFmt) == "j" {
+// print prints the given struct in the format specified by the caller, which is not text. Its JSON is not followed by
+// a newline, as it never has been.
+func (ar AssetReq) print(v any) error {
+	if oFmt := strings.ToLower(ar.oFmt); oFmt == "json" || oFmt == "j" {
 		buff := new(bytes.Buffer)
-		b, err := json.MarshalIndent(&output, "", "\t")
+		b, err := json.MarshalIndent(v, "", "\t")
 		if err != nil {
 			return errors.New("ERROR: json.MarshalIndent() returned: " + err.Error())
 		}
 		buff.Write(b)
-		_, err = buff.WriteTo(os.Stdout)
+		_, err = buff.WriteTo(ar.infoWriter())
 		if err != nil {
-			return errors.New("ERROR: buff.WriteTo(os.Stdout) returned: " + err.Error())
-		}
-	} else {
-		s := reflect.ValueOf(&output).Elem()
-		typeOfT := s.Type()
-		for i := 0; i < s.NumField(); i++ {
-			f := s.Field(i)
-			fmt.Printf("%s: %v\n",
-				typeOfT.Field(i).Name, f.Interface())
+			return errors.New("ERROR: buff.WriteTo() returned: " + err.Error())
 		}
+		return nil
 	}
-	return nil
+	return Print(ar.infoWriter(), ar.oFmt, v, v)
+}
+
+// infoWriter returns where information about the asset is printed: STDOUT, unless the asset itself is streamed.
+func (ar AssetReq) infoWriter() io.Writer {
+	if ar.dest != nil {
+		return os.Stderr
+	}
+	return os.Stdout
 }
 
-// buildReq builds an HTTP request.
-func (ar AssetReq) buildReq() (req *http.Request, err error) {
+// buildReq builds an HTTP request, and records its URL in the given output struct.
+func (ar AssetReq) buildReq(output *Output) (req *http.Request, err error) {
 	reqURL, err := ar.buildURL()
 	if err != nil {
 		return req, err
@@ -206,7 +427,9 @@ func (ar AssetReq) buildReq() (req *http.Request, err error) {
 // buildURL builds the request URL.
 func (ar AssetReq) buildURL() (urlStr string, err error) {
 	var host string
-	if ar.clientCredsType == "apim" {
+	if apiHost != "" {
+		host = apiHost
+	} else if ar.clientCredsType == "apim" {
 		host = viyaOrdersAPIAPIMHost
 	} else {
 		host = viyaOrdersAPIHost
@@ -249,60 +472,381 @@ func (ar AssetReq) buildURL() (urlStr string, err error) {
 }
 
 // makeReq makes an HTTP request for an order asset and returns the name of the file where the requested asset was saved.
-func (ar AssetReq) makeReq() (fileName string, err error) {
-	req, err := ar.buildReq()
+func (ar AssetReq) makeReq(output *Output) (fileName string, err error) {
+	start := time.Now()
+	req, err := ar.buildReq(output)
 	if err != nil {
 		return fileName, err
 	}
 
-	// Send the request.
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fileName, errors.New("ERROR: asset request failed to complete: " + err.Error())
+	// Leave a saved asset be if asked to, without asking the API when possible.
+	if ar.noClobber {
+		fileName, ok, err := ar.knownFileName()
+		if err != nil {
+			return fileName, err
+		}
+		if _, err := os.Stat(fileName); ok && err == nil {
+			output.Outcome = "exists"
+			return fileName, nil
+		}
+	}
+	var savedFile, savedRelease string
+	if ar.ifChanged && ar.aName == "deploymentAssets" {
+		savedFile, savedRelease = ar.savedFile, ar.savedRelease
+		if savedFile == "" {
+			savedFile, savedRelease, err = ar.savedAssets()
+			if err != nil {
+				return fileName, err
+			}
+		}
+		if savedRelease != "" && strings.EqualFold(savedRelease, ar.cRel) {
+			output.Outcome = "unchanged"
+			return savedFile, nil
+		}
 	}
 
-	// Handle the response.
+	// Look for a cached copy of the asset. The asset history changes with every download, so it is never cached.
+	var cached *cache.Entry
+	if ar.cache != nil && ar.aName != "assetHistory" {
+		cached, err = ar.cache.Lookup(output.AssetReqURL)
+		if err != nil {
+			return fileName, err
+		}
+	}
 
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
+	var body io.Reader
+	var contentDisp string
+	var size int64
+	var cw *cache.Writer
+	var br *bodyReader
+	if cached != nil && ar.pinned() {
+		// The deployment assets for a given cadence release never change, so there is no need to ask the API.
+		f, err := ar.cache.Open(*cached)
+		if err != nil {
+			return fileName, err
+		}
+		defer f.Close()
+		body, contentDisp, size = f, cached.ContentDisposition, cached.Size
+		output.CacheStatus = "hit"
+		slog.Debug("using cached asset without asking the API", "order", ar.oNum, "asset", ar.aName,
+			"url", output.AssetReqURL)
+	} else {
+		// Ask the API to only send the asset if it has changed since it was cached.
+		if cached != nil {
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
+
+		// Send the request.
+		client := apiclient.Client()
+		slog.Debug("sending asset request", "order", ar.oNum, "asset", ar.aName, "url", req.URL.String())
+		sent := time.Now()
+		resp, err := client.Do(req)
 		if err != nil {
-			return fileName, errors.New("ERROR: io.ReadAll() returned: " + err.Error() +
-				" on attempt to read response body from non-200 response code")
+			metrics.ObserveAPIRequest(ar.aName, 0, time.Since(sent))
+			return fileName, apierrors.Network("asset request", req.URL.String(), err)
 		}
-		em := "ERROR: asset request failed: "
-		var emErr string
-		if len(body) > 0 {
-			emErr = string(body)
-		} else {
-			emErr = fmt.Sprintf("%d -- %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		metrics.ObserveAPIRequest(ar.aName, resp.StatusCode, time.Since(sent))
+		slog.Debug("received asset response", "order", ar.oNum, "asset", ar.aName, "url", req.URL.String(),
+			"status", resp.StatusCode, "duration", time.Since(sent).Round(time.Millisecond))
+
+		// Handle the response.
+
+		defer resp.Body.Close()
+		switch {
+		case cached != nil && resp.StatusCode == http.StatusNotModified:
+			f, err := ar.cache.Open(*cached)
+			if err != nil {
+				return fileName, err
+			}
+			defer f.Close()
+			body, contentDisp, size = f, cached.ContentDisposition, cached.Size
+			output.CacheStatus = "hit"
+		case resp.StatusCode == http.StatusOK:
+			br = &bodyReader{r: resp.Body}
+			body, contentDisp, size = br, resp.Header.Get("Content-Disposition"), resp.ContentLength
+			if ar.cache != nil && ar.aName != "assetHistory" {
+				cw, err = ar.cache.Begin(cache.Entry{
+					Key:                output.AssetReqURL,
+					OrderNumber:        ar.oNum,
+					AssetName:          ar.aName,
+					ContentDisposition: contentDisp,
+					ETag:               resp.Header.Get("ETag"),
+					LastModified:       resp.Header.Get("Last-Modified"),
+				})
+				if err != nil {
+					return fileName, err
+				}
+				output.CacheStatus = "miss"
+			}
+		default:
+			return fileName, apierrors.FromResponse("asset request", resp)
 		}
-		return fileName, errors.New(em + emErr)
 	}
 
-	// Determine where on disk we will save the asset.
-	fileName, err = ar.getFileName(resp.Header.Get("Content-Disposition"))
+	// Count the bytes transferred as they are read.
+	source := "api"
+	if output.CacheStatus == "hit" {
+		source = "cache"
+	}
+	body = metrics.CountBytes(body, ar.aName, source)
+	pr := newProgressReader(body, ar.oNum, ar.aName, size, ar.progress)
+	defer pr.finish()
+	body = pr
+
+	// Determine where on disk we will save the asset. A streamed asset is not saved, but an upload of it is named
+	// after the file.
+	fileName, err = ar.getFileName(contentDisp)
 	if err != nil {
+		if cw != nil {
+			cw.Abort()
+		}
 		return fileName, err
 	}
 
-	// Save asset to disk.
-	out, err := os.Create(fileName)
+	// Now that the API has named the asset, and the deployment assets have told their release, leave a saved asset be
+	// if asked to.
+	var cadence, release string
+	if _, err := os.Stat(fileName); ar.noClobber && err == nil {
+		output.Outcome = "exists"
+	} else if savedRelease != "" || (ar.uploader != nil && ar.aName == "deploymentAssets") {
+		// The cadence information of deployment assets is also uploaded with them, before they are saved.
+		cadence, release, body = peekCadence(body, fileName)
+		if savedRelease != "" && strings.EqualFold(release, savedRelease) {
+			output.Outcome = "unchanged"
+			fileName = savedFile
+		}
+	}
+	if skipped(*output) {
+		if cw != nil {
+			cw.Abort()
+			output.CacheStatus = ""
+		}
+		return fileName, nil
+	}
+
+	var dst io.Writer
+	var out *os.File
+	if ar.dest != nil {
+		dst = ar.dest
+	} else {
+		// Save asset to disk.
+		out, err = os.Create(fileName)
+		if err != nil {
+			if cw != nil {
+				cw.Abort()
+			}
+			return fileName, errors.New("ERROR: attempt to create output file " + fileName + " failed: " + err.Error())
+		}
+		defer out.Close()
+		dst = out
+	}
+
+	// Cache the asset as it is downloaded, if requested.
+	if cw != nil {
+		dst = io.MultiWriter(dst, cw)
+	}
+
+	// Upload the asset as it is downloaded, too, if requested.
+	if ar.uploader != nil {
+		if ar.aName == "license" {
+			cadence, release, _ = ar.getCadenceInfo(fileName)
+		}
+		// The digest is known before the asset is read if it was expected, or is that of the cached asset.
+		digest := ar.expectSHA256
+		if digest == "" && output.CacheStatus == "hit" {
+			digest = strings.TrimPrefix(cached.Digest, "sha256:")
+		}
+		uw, err := ar.uploader.Begin(fileName, size, digest, map[string]string{
+			"Order-Number":    ar.oNum,
+			"Asset-Name":      ar.aName,
+			"Cadence":         cadence,
+			"Cadence-Release": release,
+		})
+		if err != nil {
+			if cw != nil {
+				cw.Abort()
+			}
+			return fileName, err
+		}
+		dst = io.MultiWriter(dst, uw)
+	}
+
+	// Compute the digests of the asset as it is written, too.
+	dg := ar.newDigester()
+	dst = io.MultiWriter(dst, dg)
+
+	if ar.dest != nil {
+		if aw, ok := ar.dest.(AssetWriter); ok {
+			aw.BeginAsset(filepath.Base(fileName), size)
+		}
+		err = ar.streamAsset(dst, body, output)
+		fileName = "-"
+	} else {
+		_, err = io.Copy(dst, body)
+		if err != nil {
+			err = errors.New("ERROR: io.Copy() returned: " + err.Error() + " on attempt to write to " + fileName)
+		}
+	}
 	if err != nil {
-		return fileName, errors.New("ERROR: attempt to create output file " + fileName + " failed: " + err.Error())
+		if br != nil && br.err != nil {
+			// The connection was lost, rather than the asset failing to be written.
+			err = apierrors.Network("asset download", req.URL.String(), br.err)
+		}
+		if ar.uploader != nil {
+			ar.uploader.Abort()
+		}
+		if cw != nil {
+			cw.Abort()
+		}
+		return fileName, err
 	}
 
-	defer out.Close()
-	_, err = io.Copy(out, resp.Body)
+	// An asset that is not what was expected is not kept.
+	dg.record(output)
+	err = ar.checkDigest(*output)
 	if err != nil {
-		return fileName, errors.New("ERROR: io.Copy() returned: " + err.Error() +
-			" on attempt to write to " + fileName)
+		if ar.uploader != nil {
+			ar.uploader.Abort()
+		}
+		if cw != nil {
+			cw.Abort()
+		}
+		if out != nil {
+			// The file is closed first, since open files cannot be removed everywhere.
+			_ = out.Close()
+			_ = os.Remove(fileName)
+		}
+		return fileName, err
+	}
+
+	if cw != nil {
+		_, err = cw.Commit()
+		if err != nil {
+			return fileName, err
+		}
+	}
+
+	if ar.noClobber || ar.ifChanged {
+		output.Outcome = "downloaded"
+	}
+
+	// Record the transfer statistics, to the millisecond.
+	d := time.Since(start).Round(time.Millisecond)
+	output.AssetSize = pr.n
+	output.DurationSeconds = float64(d.Milliseconds()) / 1000
+	if d > 0 {
+		output.BytesPerSecond = int64(float64(pr.n) / d.Seconds())
 	}
 
 	return fileName, nil
 }
 
+// pinned returns whether the AssetReq receiver asks for assets that never change: the deployment assets for a given
+// cadence release.
+func (ar AssetReq) pinned() bool {
+	return ar.aName == "deploymentAssets" && ar.cVer != "" && ar.cRel != ""
+}
+
+// streamAsset copies the asset in the given response body to the given writer. Deployment assets cannot be read again
+// afterwards, so their cadence information is extracted as they pass through and recorded in the given output struct.
+func (ar AssetReq) streamAsset(w io.Writer, body io.Reader, output *Output) error {
+	if ar.aName != "deploymentAssets" {
+		_, err := io.Copy(w, body)
+		if err != nil {
+			return errors.New("ERROR: io.Copy() returned: " + err.Error() + " on attempt to stream asset")
+		}
+		return nil
+	}
+
+	cs := newCadenceSniffer("deployment assets")
+	_, err := io.Copy(io.MultiWriter(w, cs), body)
+	if err != nil {
+		_, _, _ = cs.close()
+		return errors.New("ERROR: io.Copy() returned: " + err.Error() + " on attempt to stream asset")
+	}
+	output.Cadence, output.CadenceRelease, err = cs.close()
+	return err
+}
+
+// cadenceSniffer is a writer that extracts the cadence information from the deployment assets written to it.
+type cadenceSniffer struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	cVal string
+	cRel string
+	err  error
+}
+
+// newCadenceSniffer starts a cadenceSniffer. The name identifies the deployment assets in error messages.
+func newCadenceSniffer(name string) *cadenceSniffer {
+	pr, pw := io.Pipe()
+	cs := &cadenceSniffer{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(cs.done)
+		cs.cVal, cs.cRel, cs.err = readCadence(pr, name)
+		// Keep reading whatever is left so that writes to the sniffer never block.
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+	return cs
+}
+
+// Write passes the given bytes to the goroutine that extracts the cadence information.
+func (cs *cadenceSniffer) Write(p []byte) (int, error) {
+	return cs.pw.Write(p)
+}
+
+// close signals that all of the deployment assets were written and returns the cadence information.
+func (cs *cadenceSniffer) close() (string, string, error) {
+	_ = cs.pw.Close()
+	<-cs.done
+	return cs.cVal, cs.cRel, cs.err
+}
+
+// VerifyCreds requests the order asset defined in the AssetReq receiver without saving it, to confirm that the
+// SAS Viya Orders API accepts the client credentials.
+func (ar AssetReq) VerifyCreds() error {
+	req, err := ar.buildReq(&Output{})
+	if err != nil {
+		return err
+	}
+
+	client := apiclient.Client()
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		metrics.ObserveAPIRequest(ar.aName, 0, time.Since(sent))
+		return apierrors.Network("credential check request", req.URL.String(), err)
+	}
+	metrics.ObserveAPIRequest(ar.aName, resp.StatusCode, time.Since(sent))
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apierrors.FromResponse("credential check", resp)
+	}
+
+	return nil
+}
+
+// bodyReader remembers the error that reading a response body failed with, to tell a lost connection apart from a
+// failure to write the asset.
+type bodyReader struct {
+	r   io.Reader
+	err error
+}
+
+func (br *bodyReader) Read(p []byte) (int, error) {
+	n, err := br.r.Read(p)
+	if err != nil && err != io.EOF {
+		br.err = err
+	}
+	return n, err
+}
+
 // getCadenceInfo gets the cadence name, version, and release, if applicable, for the retrieved order asset.
 func (ar AssetReq) getCadenceInfo(file string) (string, string, error) {
 	// Cadence release is only applicable to deployment assets.
@@ -319,7 +863,13 @@ func (ar AssetReq) getCadenceInfo(file string) (string, string, error) {
 	}
 
 	defer f.Close()
-	gzf, err := gzip.NewReader(f)
+	return readCadence(f, file)
+}
+
+// readCadence extracts the cadence information from the deployment assets in the given reader. The name identifies the
+// deployment assets in error messages.
+func readCadence(r io.Reader, file string) (string, string, error) {
+	gzf, err := gzip.NewReader(r)
 	if err != nil {
 		return "", "", errors.New("ERROR: prepare to read " + file + " failed: " + err.Error())
 	}
@@ -335,30 +885,46 @@ func (ar AssetReq) getCadenceInfo(file string) (string, string, error) {
 		}
 
 		if header.Name == checksumsFile {
+			// The header can claim any size, so do not believe more than the deployment assets are peeked at.
+			if header.Size < 0 || header.Size > maxPeek {
+				return "", "", errors.New("ERROR: " + checksumsFile + " in " + file + " is too large (" +
+					strconv.FormatInt(header.Size, 10) + " bytes)")
+			}
 			data := make([]byte, header.Size)
-			_, err := tarReader.Read(data)
+			_, err := io.ReadFull(tarReader, data)
 			if err != nil {
 				return "", "", errors.New("ERROR: attempt to read " + checksumsFile + " failed: " + err.Error())
 			}
-			cVal, cRel := extractCadence(data)
+			cVal, cRel, err := extractCadence(data)
+			if err != nil {
+				return "", "", errors.New("ERROR: " + checksumsFile + " in " + file + " " + err.Error())
+			}
 			return cVal, cRel, nil
 		}
 	}
 }
 
-// extractCadence finds and returns the cadence information in the given byte array.
-func extractCadence(data []byte) (string, string) {
-	cLabelSt := bytes.Index(data, []byte("Cadence Display Name:"))
-	tempData := data[cLabelSt:]
-	fields := bytes.Fields(tempData)
-	cValueSt := bytes.Index(tempData, fields[3])
-	cValueEnd := bytes.Index(tempData, []byte("\n"))
-	cValue := string(tempData[cValueSt:cValueEnd])
-
-	cRelSt := bytes.Index(data, []byte("Cadence Release:"))
-	tempData = data[cRelSt:]
-	fields = bytes.Fields(tempData)
-	cRel := string(fields[2])
+// extractCadence finds and returns the cadence information in the given byte array, or an error if it is not all
+// there.
+func extractCadence(data []byte) (string, string, error) {
+	cValue := labelValue(data, "Cadence Display Name:")
+	if cValue == "" {
+		return "", "", errors.New("has no cadence display name")
+	}
+	fields := strings.Fields(labelValue(data, "Cadence Release:"))
+	if len(fields) == 0 {
+		return "", "", errors.New("has no cadence release")
+	}
+	return cValue, fields[0], nil
+}
 
-	return cValue, cRel
+// labelValue returns the rest of the line that follows the given label in the given byte array, without surrounding
+// white space, or an empty string if the label is not found.
+func labelValue(data []byte, label string) string {
+	_, after, found := bytes.Cut(data, []byte(label))
+	if !found {
+		return ""
+	}
+	line, _, _ := bytes.Cut(after, []byte("\n"))
+	return string(bytes.TrimSpace(line))
 }
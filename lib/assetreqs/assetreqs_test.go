@@ -0,0 +1,211 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/apierrors"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/orderstest"
+	"github.com/sassoftware/viya4-orders-cli/lib/provenance"
+)
+
+const (
+	orderNum      = "923457"
+	latestCadence = "Stable 2026.01"
+	latestRelease = "20260215.1771111111111"
+)
+
+// startAPI starts a mock of the SAS Viya Orders API that asset requests go to until the test is done.
+func startAPI(t *testing.T) *orderstest.Server {
+	t.Helper()
+	api, err := orderstest.NewServer(orderstest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assetreqs.SetAPIHost(api.URL)
+	t.Cleanup(func() {
+		assetreqs.SetAPIHost("")
+		api.Close()
+	})
+	return api
+}
+
+// newReq returns a request for the given asset of the mock order, to be saved in the given directory.
+func newReq(assetName, cadenceName, cadenceVer, cadenceRel, dir string) assetreqs.AssetReq {
+	return assetreqs.New("apim", "", "id", "secret", assetName, orderNum, cadenceName, cadenceVer, cadenceRel, dir,
+		"", "json", false)
+}
+
+func TestFetch(t *testing.T) {
+	startAPI(t)
+	for _, tc := range []struct {
+		asset, cadenceName, cadenceVer, cadenceRel string
+		cadence, release                           string
+	}{
+		{asset: "deploymentAssets", cadenceName: "stable", cadence: latestCadence, release: latestRelease},
+		{asset: "deploymentAssets", cadenceName: "lts", cadence: "LTS 2025.09", release: "20250930.1759190400000"},
+		{asset: "deploymentAssets", cadenceName: "stable", cadenceVer: "2026.01", cadenceRel: "20260127.1769510312235",
+			cadence: latestCadence, release: "20260127.1769510312235"},
+		{asset: "license", cadenceName: "stable", cadenceVer: "2026.01", cadence: latestCadence},
+		{asset: "certificates"},
+		{asset: "assetHistory"},
+	} {
+		dir := t.TempDir()
+		output, err := newReq(tc.asset, tc.cadenceName, tc.cadenceVer, tc.cadenceRel, dir).Fetch()
+		if err != nil {
+			t.Errorf("Fetch of %s %s %s %s returned %v", tc.asset, tc.cadenceName, tc.cadenceVer, tc.cadenceRel, err)
+			continue
+		}
+		if output.Cadence != tc.cadence || output.CadenceRelease != tc.release {
+			t.Errorf("Fetch of %s %s %s %s reported cadence %q release %q, want %q %q", tc.asset, tc.cadenceName,
+				tc.cadenceVer, tc.cadenceRel, output.Cadence, output.CadenceRelease, tc.cadence, tc.release)
+		}
+		if filepath.Dir(output.AssetLocation) != dir {
+			t.Errorf("Fetch of %s saved the asset to %s, not in %s", tc.asset, output.AssetLocation, dir)
+			continue
+		}
+		b, err := os.ReadFile(output.AssetLocation)
+		if err != nil {
+			t.Errorf("Fetch of %s did not save the asset: %v", tc.asset, err)
+			continue
+		}
+		sum := sha256.Sum256(b)
+		if output.AssetSize != int64(len(b)) || output.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("Fetch of %s reported size %d digest %s for an asset of size %d digest %x", tc.asset,
+				output.AssetSize, output.SHA256, len(b), sum)
+		}
+		m, err := provenance.Read(provenance.File(output.AssetLocation))
+		if err != nil {
+			t.Errorf("Fetch of %s did not record where the asset came from: %v", tc.asset, err)
+		} else if m.SHA256 != output.SHA256 || m.Size != output.AssetSize || m.AssetReqURL != output.AssetReqURL {
+			t.Errorf("Fetch of %s recorded %+v for %+v", tc.asset, m, output)
+		}
+	}
+}
+
+func TestFetchStreamed(t *testing.T) {
+	startAPI(t)
+	for _, tc := range []struct {
+		cadenceVer, cadenceRel string
+		release                string
+	}{
+		{release: latestRelease},
+		{cadenceVer: "2026.01", cadenceRel: "20260127.1769510312235", release: "20260127.1769510312235"},
+	} {
+		// Streamed deployment assets are not saved, so their cadence information is sniffed as they pass through.
+		var buf bytes.Buffer
+		output, err := newReq("deploymentAssets", "stable", tc.cadenceVer, tc.cadenceRel, "").WithWriter(&buf).Fetch()
+		if err != nil {
+			t.Errorf("streamed Fetch of release %q returned %v", tc.cadenceRel, err)
+			continue
+		}
+		if output.AssetLocation != "-" || buf.Len() == 0 || output.AssetSize != int64(buf.Len()) {
+			t.Errorf("streamed Fetch of release %q reported location %s size %d for %d bytes streamed",
+				tc.cadenceRel, output.AssetLocation, output.AssetSize, buf.Len())
+		}
+		if output.Cadence != latestCadence || output.CadenceRelease != tc.release {
+			t.Errorf("streamed Fetch of release %q reported cadence %q release %q, want %q %q", tc.cadenceRel,
+				output.Cadence, output.CadenceRelease, latestCadence, tc.release)
+		}
+	}
+}
+
+func TestFetchFaults(t *testing.T) {
+	api := startAPI(t)
+	for _, tc := range []struct {
+		name                           string
+		order, cadenceName, cadenceVer string
+		asset                          string
+		fault                          *orderstest.Fault
+		kind                           error
+	}{
+		{name: "unknown order", order: "999999", asset: "certificates", kind: apierrors.ErrOrderNotFound},
+		{name: "unknown order of a cadence", order: "999999", asset: "deploymentAssets", cadenceName: "stable",
+			kind: apierrors.ErrNotFound},
+		{name: "unknown cadence", asset: "license", cadenceName: "stable", cadenceVer: "1999.01",
+			kind: apierrors.ErrNotFound},
+		{name: "unsupported cadence", asset: "license", cadenceName: "lts", cadenceVer: "2024.09",
+			kind: apierrors.ErrCadenceUnsupported},
+		{name: "credentials not accepted", asset: "certificates",
+			fault: &orderstest.Fault{Status: http.StatusUnauthorized}, kind: apierrors.ErrUnauthorized},
+		{name: "too many requests", asset: "certificates",
+			fault: &orderstest.Fault{Status: http.StatusTooManyRequests}, kind: apierrors.ErrRateLimited},
+		{name: "server failure", asset: "deploymentAssets", cadenceName: "stable",
+			fault: &orderstest.Fault{Status: http.StatusServiceUnavailable}, kind: apierrors.ErrServer},
+		{name: "truncated body", asset: "deploymentAssets", cadenceName: "stable",
+			fault: &orderstest.Fault{Truncate: true}, kind: apierrors.ErrNetwork},
+		{name: "slow body", asset: "deploymentAssets", cadenceName: "stable",
+			fault: &orderstest.Fault{SlowBody: 100 * time.Millisecond}},
+	} {
+		api.ClearFaults()
+		if tc.fault != nil {
+			api.InjectFault(*tc.fault)
+		}
+		ar := newReq(tc.asset, tc.cadenceName, tc.cadenceVer, "", t.TempDir())
+		if tc.order != "" {
+			ar = assetreqs.New("apim", "", "id", "secret", tc.asset, tc.order, tc.cadenceName, tc.cadenceVer, "",
+				t.TempDir(), "", "json", false)
+		}
+		_, err := ar.Fetch()
+		switch {
+		case tc.kind == nil && err != nil:
+			t.Errorf("%s: Fetch returned %v", tc.name, err)
+		case tc.kind != nil && !errors.Is(err, tc.kind):
+			t.Errorf("%s: Fetch returned %v, want an error of kind %v", tc.name, err, tc.kind)
+		}
+		var ae *apierrors.Error
+		if tc.kind == apierrors.ErrRateLimited && (!errors.As(err, &ae) || ae.RetryAfter != time.Second) {
+			t.Errorf("%s: Fetch returned %v, want an error with Retry-After 1s", tc.name, err)
+		}
+	}
+}
+
+func TestFetchStalled(t *testing.T) {
+	api := startAPI(t)
+	err := apiclient.Configure(apiclient.Config{StallTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer apiclient.Configure(apiclient.Config{})
+
+	// The body is sent in 20 pieces, one every 250ms.
+	api.InjectFault(orderstest.Fault{SlowBody: 5 * time.Second})
+	_, err = newReq("deploymentAssets", "stable", "", "", t.TempDir()).Fetch()
+	var te *apiclient.TimeoutError
+	if !errors.Is(err, apierrors.ErrNetwork) || !errors.As(err, &te) || te.Kind != apiclient.TimeoutStall {
+		t.Errorf("Fetch of a stalled download returned %v, want a stall TimeoutError", err)
+	}
+}
+
+func TestFetchExpectedDigest(t *testing.T) {
+	startAPI(t)
+	dir := t.TempDir()
+	output, err := newReq("certificates", "", "", "", dir).Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = newReq("certificates", "", "", "", dir).WithExpectedSHA256(output.SHA256).Fetch(); err != nil {
+		t.Errorf("Fetch with the right digest returned %v", err)
+	}
+
+	_, err = newReq("certificates", "", "", "", dir).WithExpectedSHA256(hex.EncodeToString(make([]byte, 32))).Fetch()
+	var de *assetreqs.DigestError
+	if !errors.Is(err, assetreqs.ErrDigestMismatch) || !errors.As(err, &de) || de.Actual != output.SHA256 {
+		t.Errorf("Fetch with the wrong digest returned %v, want a DigestError", err)
+	}
+	if _, err = os.Stat(output.AssetLocation); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Fetch with the wrong digest kept the asset: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrDigestMismatch is the kind of error returned when an asset does not have the expected digest. Use errors.Is to
+// check whether an error is of this kind.
+var ErrDigestMismatch = errors.New("the asset does not have the expected digest")
+
+// DigestError is an asset whose SHA-256 digest is not the one that was expected.
+type DigestError struct {
+	Asset    string // the name of the asset, for example deploymentAssets
+	Expected string
+	Actual   string
+}
+
+func (e *DigestError) Error() string {
+	return "ERROR: the SHA-256 digest of the " + e.Asset + " is " + e.Actual + ", not " + e.Expected + " as expected"
+}
+
+func (e *DigestError) Unwrap() error {
+	return ErrDigestMismatch
+}
+
+// WithSHA512 returns a copy of the AssetReq receiver that reports the SHA-512 digest of the asset as well as its
+// SHA-256 digest.
+func (ar AssetReq) WithSHA512() AssetReq {
+	ar.sha512 = true
+	return ar
+}
+
+// WithExpectedSHA256 returns a copy of the AssetReq receiver that fails with a DigestError if the SHA-256 digest of the
+// asset, in hex, is not the given one. An asset that was downloaded is then not kept.
+func (ar AssetReq) WithExpectedSHA256(digest string) AssetReq {
+	ar.expectSHA256 = strings.ToLower(digest)
+	return ar
+}
+
+// digester computes the digests of an asset as it is written.
+type digester struct {
+	sha256 hash.Hash
+	sha512 hash.Hash // nil unless the SHA-512 digest was asked for
+}
+
+// newDigester returns a digester for the digests that the AssetReq receiver reports.
+func (ar AssetReq) newDigester() *digester {
+	d := &digester{sha256: sha256.New()}
+	if ar.sha512 {
+		d.sha512 = sha512.New()
+	}
+	return d
+}
+
+func (d *digester) Write(p []byte) (int, error) {
+	d.sha256.Write(p)
+	if d.sha512 != nil {
+		d.sha512.Write(p)
+	}
+	return len(p), nil
+}
+
+// record records the digests of what was written in the given output struct.
+func (d *digester) record(output *Output) {
+	output.SHA256 = hex.EncodeToString(d.sha256.Sum(nil))
+	if d.sha512 != nil {
+		output.SHA512 = hex.EncodeToString(d.sha512.Sum(nil))
+	}
+}
+
+// digestFile records the digests of the given saved asset file in the given output struct.
+func (ar AssetReq) digestFile(file string, output *Output) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return errors.New("ERROR: attempt to open " + file + " failed: " + err.Error())
+	}
+	defer f.Close()
+	d := ar.newDigester()
+	_, err = io.Copy(d, f)
+	if err != nil {
+		return errors.New("ERROR: attempt to read " + file + " failed: " + err.Error())
+	}
+	d.record(output)
+	return nil
+}
+
+// checkDigest returns a DigestError if the asset described by the given output struct does not have the SHA-256
+// digest that the AssetReq receiver expects.
+func (ar AssetReq) checkDigest(output Output) error {
+	if ar.expectSHA256 == "" || output.SHA256 == ar.expectSHA256 {
+		return nil
+	}
+	return &DigestError{Asset: ar.aName, Expected: ar.expectSHA256, Actual: output.SHA256}
+}
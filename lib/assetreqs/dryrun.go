@@ -0,0 +1,153 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+)
+
+// assetExts are the extensions of the files that the SAS Viya Orders API names the assets with.
+var assetExts = map[string]string{
+	"deploymentAssets": ".tgz",
+	"license":          ".jwt",
+	"certificates":     ".zip",
+	"assetHistory":     ".json",
+}
+
+// Plan describes the request for an order asset that would be made, printed instead of making it in a dry run.
+type Plan struct {
+	OrderNumber   string            `json:"orderNumber" yaml:"orderNumber"`
+	AssetName     string            `json:"assetName" yaml:"assetName"`
+	Method        string            `json:"method" yaml:"method"`
+	AssetReqURL   string            `json:"assetReqURL" yaml:"assetReqURL"`
+	AuthMode      string            `json:"authMode" yaml:"authMode"`
+	Headers       map[string]string `json:"headers" yaml:"headers"`
+	AssetLocation string            `json:"assetLocation" yaml:"assetLocation"`
+}
+
+// WithDryRun returns a copy of the AssetReq receiver that, instead of requesting the asset, prints the request that
+// would be made and where the asset would be saved.
+func (ar AssetReq) WithDryRun() AssetReq {
+	ar.dryRun = true
+	return ar
+}
+
+// Plan returns the request for the order asset defined in the AssetReq receiver, without making it.
+func (ar AssetReq) Plan() (Plan, error) {
+	req, err := ar.buildReq(&Output{})
+	if err != nil {
+		return Plan{}, err
+	}
+
+	p := Plan{
+		OrderNumber: ar.oNum,
+		AssetName:   ar.aName,
+		Method:      req.Method,
+		AssetReqURL: req.URL.String(),
+		AuthMode:    "Apigee",
+		Headers:     map[string]string{},
+	}
+	if ar.clientCredsType == "apim" {
+		p.AuthMode = "APIM"
+	}
+	// The credentials are never shown, only whether they are set.
+	for name, values := range req.Header {
+		v := strings.Join(values, ", ")
+		switch {
+		case name == "Authorization" && ar.token == "":
+			// A dry run does not request a Bearer token.
+			v = "Bearer (requested when the asset is)"
+		case name == "Authorization":
+			v = "Bearer " + apiclient.Redacted
+		case name == "ClientId" || name == "ClientSecret":
+			if v != "" {
+				v = apiclient.Redacted
+			}
+		}
+		if v == "" {
+			v = "(not set)"
+		}
+		p.Headers[name] = v
+	}
+
+	// Streamed assets, and asset histories that are printed, are not saved.
+	if ar.dest != nil || ar.histFilter != nil {
+		p.AssetLocation = "-"
+		return p, nil
+	}
+	// Most assets are named by the API, with the date and time of the request among other things.
+	var apiFNm string
+	switch ar.aName {
+	case "certificates":
+		apiFNm = "SASViyaV4_" + ar.oNum + "_certs.zip"
+	case "assetHistory":
+		apiFNm = ar.oNum + "_assetHistory.json"
+	default:
+		apiFNm = "{name given by the SAS Viya Orders API}" + assetExts[ar.aName]
+	}
+	p.AssetLocation, err = ar.filePath(apiFNm)
+	if err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// printPlan prints the request for the order asset defined in the AssetReq receiver, without making it, in the format
+// specified by the caller.
+func (ar AssetReq) printPlan() error {
+	p, err := ar.Plan()
+	if err != nil {
+		return err
+	}
+
+	oFmt := strings.ToLower(ar.oFmt)
+	if oFmt != "" && oFmt != "text" && oFmt != "t" {
+		return ar.print(&p)
+	}
+
+	w := ar.infoWriter()
+	fmt.Fprintf(w, "OrderNumber: %s\nAssetName: %s\nMethod: %s\nAssetReqURL: %s\nAuthMode: %s\nHeaders:\n",
+		p.OrderNumber, p.AssetName, p.Method, p.AssetReqURL, p.AuthMode)
+	for _, name := range slices.Sorted(maps.Keys(p.Headers)) {
+		fmt.Fprintf(w, "  %s: %s\n", name, p.Headers[name])
+	}
+	_, err = fmt.Fprintf(w, "AssetLocation: %s\n", p.AssetLocation)
+	if err != nil {
+		return errors.New("ERROR: attempt to print dry run failed: " + err.Error())
+	}
+	return nil
+}
+
+// filePath returns where an asset that the API names as given would be saved.
+func (ar AssetReq) filePath(apiFileName string) (string, error) {
+	dir, err := ar.dir()
+	if err != nil {
+		return "", err
+	}
+	if ar.fName != "" {
+		// Even if they specified -n, use the extension that the API returned
+		return filepath.Join(dir, ar.fName) + filepath.Ext(apiFileName), nil
+	}
+	return filepath.Join(dir, apiFileName), nil
+}
+
+// dir returns the directory where assets are saved.
+func (ar AssetReq) dir() (string, error) {
+	if ar.fPath != "" {
+		return ar.fPath, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", errors.New("ERROR: os.Getwd() returned: " + err.Error())
+	}
+	return dir, nil
+}
@@ -0,0 +1,160 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/orderstest"
+)
+
+const (
+	secretID     = "c2VjcmV0LWlk"
+	secretSecret = "c2VjcmV0LXNlY3JldA"
+	secretToken  = "eyJzZWNyZXQtdG9rZW4"
+)
+
+// startCountingAPI starts a mock of the SAS Viya Orders API that asset requests go to until the test is done, and
+// returns the number of requests that reach it.
+func startCountingAPI(t *testing.T) *atomic.Int32 {
+	t.Helper()
+	api, err := orderstest.New(orderstest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		api.ServeHTTP(w, r)
+	}))
+	assetreqs.SetAPIHost(srv.URL)
+	t.Cleanup(func() {
+		assetreqs.SetAPIHost("")
+		srv.Close()
+	})
+	return &hits
+}
+
+// captureStdout returns what the given function prints to STDOUT.
+func captureStdout(t *testing.T, f func() error) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		done <- b
+	}()
+	err = f()
+	w.Close()
+	return string(<-done), err
+}
+
+func TestPlan(t *testing.T) {
+	hits := startCountingAPI(t)
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name    string
+		ar      assetreqs.AssetReq
+		url     string
+		auth    string
+		headers map[string]string
+		loc     string
+	}{
+		{name: "APIM credentials",
+			ar: assetreqs.New("apim", "", secretID, secretSecret, "license", orderNum, "stable", "2026.01", "", dir,
+				"", "json", false),
+			url: "/mysas/orders/923457/cadenceNames/stable/cadenceVersions/2026.01/license", auth: "APIM",
+			headers: map[string]string{"ClientId": apiclient.Redacted, "ClientSecret": apiclient.Redacted},
+			loc:     filepath.Join(dir, "{name given by the SAS Viya Orders API}.jwt")},
+		{name: "APIM credentials without a secret",
+			ar: assetreqs.New("apim", "", secretID, "", "certificates", orderNum, "", "", "", dir, "certs", "json",
+				false),
+			url: "/mysas/orders/923457/certificates", auth: "APIM",
+			headers: map[string]string{"ClientId": apiclient.Redacted, "ClientSecret": "(not set)"},
+			loc:     filepath.Join(dir, "certs.zip")},
+		{name: "Apigee credentials with a Bearer token",
+			ar: assetreqs.New("apigee", secretToken, secretID, secretSecret, "deploymentAssets", orderNum, "stable", "",
+				"", dir, "", "json", true),
+			url: "/mysas/orders/923457/cadenceNames/stable/deploymentAssets?allowUnsupported=true", auth: "Apigee",
+			headers: map[string]string{"Authorization": "Bearer " + apiclient.Redacted},
+			loc:     filepath.Join(dir, "{name given by the SAS Viya Orders API}.tgz")},
+		{name: "Apigee credentials before the Bearer token is requested",
+			ar: assetreqs.New("apigee", "", secretID, secretSecret, "certificates", orderNum, "", "", "", "", "",
+				"json", false).WithWriter(io.Discard),
+			url: "/mysas/orders/923457/certificates", auth: "Apigee",
+			headers: map[string]string{"Authorization": "Bearer (requested when the asset is)"}, loc: "-"},
+	} {
+		p, err := tc.ar.Plan()
+		if err != nil {
+			t.Errorf("%s: Plan returned %v", tc.name, err)
+			continue
+		}
+		if p.OrderNumber != orderNum || p.Method != http.MethodGet || !strings.HasSuffix(p.AssetReqURL, tc.url) ||
+			p.AuthMode != tc.auth || p.AssetLocation != tc.loc {
+			t.Errorf("%s: Plan returned %+v", tc.name, p)
+		}
+		if len(p.Headers) != len(tc.headers) {
+			t.Errorf("%s: Plan returned the headers %v, want %v", tc.name, p.Headers, tc.headers)
+		}
+		for k, v := range tc.headers {
+			if p.Headers[k] != v {
+				t.Errorf("%s: Plan returned the header %s: %q, want %q", tc.name, k, p.Headers[k], v)
+			}
+		}
+	}
+
+	if n := hits.Load(); n != 0 {
+		t.Errorf("planning the requests sent %d requests to the API", n)
+	}
+}
+
+func TestGetAssetDryRun(t *testing.T) {
+	hits := startCountingAPI(t)
+	dir := t.TempDir()
+	for _, oFmt := range []string{"text", "json", "yaml", "csv", "go-template={{.headers}}"} {
+		for _, ar := range []assetreqs.AssetReq{
+			assetreqs.New("apim", "", secretID, secretSecret, "deploymentAssets", orderNum, "stable", "", "", dir, "",
+				oFmt, false),
+			assetreqs.New("apigee", secretToken, secretID, secretSecret, "license", orderNum, "stable", "2026.01", "",
+				dir, "", oFmt, false),
+		} {
+			printed, err := captureStdout(t, ar.WithDryRun().GetAsset)
+			if err != nil {
+				t.Errorf("dry run in %s returned %v", oFmt, err)
+				continue
+			}
+			if !strings.Contains(printed, apiclient.Redacted) {
+				t.Errorf("dry run in %s does not show that the credentials are set:\n%s", oFmt, printed)
+			}
+			for _, secret := range []string{secretID, secretSecret, secretToken} {
+				if strings.Contains(printed, secret) {
+					t.Errorf("dry run in %s printed the credential %s:\n%s", oFmt, secret, printed)
+				}
+			}
+		}
+	}
+
+	// Nothing was requested or saved.
+	if n := hits.Load(); n != 0 {
+		t.Errorf("dry runs sent %d requests to the API", n)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("dry runs saved %v (%v)", entries, err)
+	}
+}
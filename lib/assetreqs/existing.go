@@ -0,0 +1,142 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// WithIfChanged returns a copy of the AssetReq receiver that, for a deploymentAssets request, does not download the
+// deployment assets if those already saved for the requested cadence are of the same release. The saved deployment
+// assets are then reported, with an Outcome of "unchanged".
+func (ar AssetReq) WithIfChanged() AssetReq {
+	ar.ifChanged = true
+	return ar
+}
+
+// WithSavedAssets returns a copy of the AssetReq receiver that, for a deploymentAssets request, does not download the
+// deployment assets if they are of the given release, which the given file already holds. The file is then reported,
+// with an Outcome of "unchanged", as with WithIfChanged.
+func (ar AssetReq) WithSavedAssets(file, release string) AssetReq {
+	ar.ifChanged = true
+	ar.savedFile = file
+	ar.savedRelease = release
+	return ar
+}
+
+// WithNoClobber returns a copy of the AssetReq receiver that never overwrites a saved asset. If the asset would be saved
+// to a file that already exists, that file is reported instead, with an Outcome of "exists".
+func (ar AssetReq) WithNoClobber() AssetReq {
+	ar.noClobber = true
+	return ar
+}
+
+// skipped returns whether the asset described by the given output struct was left as it was saved before rather than
+// downloaded.
+func skipped(output Output) bool {
+	return output.Outcome == "unchanged" || output.Outcome == "exists"
+}
+
+// knownFileName returns where the asset will be saved, if that is known before the API names the file: when the caller
+// named it, or when the API always gives it the same name.
+func (ar AssetReq) knownFileName() (string, bool, error) {
+	var apiFNm string
+	switch {
+	case ar.fName != "":
+		// Only the extension of the name that the API gives is used.
+		apiFNm = ar.fName + assetExts[ar.aName]
+	case ar.aName == "certificates":
+		apiFNm = "SASViyaV4_" + ar.oNum + "_certs.zip"
+	case ar.aName == "assetHistory":
+		apiFNm = ar.oNum + "_assetHistory.json"
+	default:
+		return "", false, nil
+	}
+	fileName, err := ar.filePath(apiFNm)
+	return fileName, err == nil, err
+}
+
+// savedAssets returns the file holding the most recently saved deployment assets for the requested order and cadence,
+// and their cadence release, or empty strings if there are none.
+func (ar AssetReq) savedAssets() (file, release string, err error) {
+	var candidates []string
+	if fileName, ok, err := ar.knownFileName(); err != nil {
+		return "", "", err
+	} else if ok {
+		candidates = []string{fileName}
+	} else {
+		dir, err := ar.dir()
+		if err != nil {
+			return "", "", err
+		}
+		// The API names deployment assets after the order, and the time they were requested.
+		candidates, err = filepath.Glob(filepath.Join(dir, "SASViyaV4_"+ar.oNum+"_*deploymentAssets*.tgz"))
+		if err != nil {
+			return "", "", errors.New("ERROR: attempt to look for saved deployment assets failed: " + err.Error())
+		}
+	}
+
+	// Look at the most recently modified files first.
+	modTimes := map[string]time.Time{}
+	for _, c := range candidates {
+		if fi, err := os.Stat(c); err == nil && fi.Mode().IsRegular() {
+			modTimes[c] = fi.ModTime()
+		}
+	}
+	candidates = slices.DeleteFunc(candidates, func(c string) bool { return modTimes[c].IsZero() })
+	slices.SortFunc(candidates, func(a, b string) int { return modTimes[b].Compare(modTimes[a]) })
+
+	for _, c := range candidates {
+		f, err := os.Open(c)
+		if err != nil {
+			return "", "", errors.New("ERROR: attempt to open " + c + " failed: " + err.Error())
+		}
+		cadence, release, err := readCadence(f, c)
+		f.Close()
+		if err != nil {
+			// Whatever the file holds, it is not deployment assets whose release is known.
+			slog.Warn("could not read the cadence release of saved deployment assets", "file", c, "error",
+				strings.TrimPrefix(err.Error(), "ERROR: "))
+			continue
+		}
+		if ar.sameCadence(cadence) {
+			return c, release, nil
+		}
+	}
+	return "", "", nil
+}
+
+// sameCadence returns whether the given cadence, as named in checksums.txt (such as Stable 2025.01), is the one
+// requested: of the requested cadence name and, if one was requested, version.
+func (ar AssetReq) sameCadence(cadence string) bool {
+	if ar.cVer != "" {
+		return strings.EqualFold(cadence, ar.cName+" "+ar.cVer)
+	}
+	return strings.HasPrefix(strings.ToLower(cadence), strings.ToLower(ar.cName)+" ")
+}
+
+// maxPeek is the most of deployment assets that peekCadence holds in memory while it looks for their cadence
+// information.
+const maxPeek int64 = 8 << 20
+
+// peekCadence reads the given deployment assets as far as their cadence information, and returns their cadence and
+// cadence release, and a reader that reads the deployment assets from the start. The cadence and release are empty if
+// they could not be read within maxPeek bytes, so that deployment assets which are not as expected are downloaded as
+// usual rather than held in memory.
+func peekCadence(body io.Reader, name string) (string, string, io.Reader) {
+	var read bytes.Buffer
+	cadence, release, err := readCadence(io.TeeReader(io.LimitReader(body, maxPeek), &read), name)
+	if err != nil {
+		cadence, release = "", ""
+	}
+	return cadence, release, io.MultiReader(&read, body)
+}
@@ -0,0 +1,166 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarball returns deployment assets of the given release, with checksums.txt after a file of the given size.
+func tarball(t *testing.T, release string, padding int) []byte {
+	t.Helper()
+	return tarballWith(t, "Cadence Display Name: Stable 2026.01\nCadence Release: "+release+"\n\n", padding)
+}
+
+// tarballWith returns deployment assets with the given checksums.txt after a file of the given size.
+func tarballWith(t *testing.T, checksums string, padding int) []byte {
+	t.Helper()
+	pad := make([]byte, padding)
+	// Random data cannot be compressed, so the padding takes up as much of the deployment assets as it says.
+	if _, err := rand.Read(pad); err != nil {
+		t.Fatal(err)
+	}
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"sas-bases/padding.bin", pad},
+		{checksumsFile, []byte(checksums)},
+	}
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for _, f := range files {
+		err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: f.name, Mode: 0644, Size: int64(len(f.data))})
+		if err == nil {
+			_, err = tw.Write(f.data)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := tw.Close()
+	if err == nil {
+		err = gw.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func TestPeekCadence(t *testing.T) {
+	for _, tc := range []struct {
+		padding          int
+		cadence, release string
+	}{
+		{0, "Stable 2026.01", "20260215.1771111111111"},
+		{1 << 20, "Stable 2026.01", "20260215.1771111111111"},
+		// The release is too far in to be peeked at, so the deployment assets are downloaded as usual.
+		{int(maxPeek) + 1<<20, "", ""},
+	} {
+		assets := tarball(t, "20260215.1771111111111", tc.padding)
+		body := &countingReader{r: bytes.NewReader(assets)}
+		cadence, release, r := peekCadence(body, "deployment assets")
+		if cadence != tc.cadence || release != tc.release {
+			t.Errorf("peekCadence with %d bytes before checksums.txt returned cadence %q release %q, want %q %q",
+				tc.padding, cadence, release, tc.cadence, tc.release)
+		}
+		if body.n > maxPeek {
+			t.Errorf("peekCadence with %d bytes before checksums.txt read %d bytes, more than %d", tc.padding,
+				body.n, maxPeek)
+		}
+		b, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(b, assets) {
+			t.Errorf("peekCadence with %d bytes before checksums.txt returned a reader of %d bytes (%v), want %d",
+				tc.padding, len(b), err, len(assets))
+		}
+	}
+}
+
+func TestReadCadence(t *testing.T) {
+	for _, tc := range []struct {
+		checksums        string
+		cadence, release string
+	}{
+		{"Cadence Display Name: Stable 2026.01\nCadence Release: 20260215.1771111111111\n", "Stable 2026.01",
+			"20260215.1771111111111"},
+		{"Cadence Display Name:  LTS 2025.09\r\nCadence Release: 20250930.1759190400000\r\n", "LTS 2025.09",
+			"20250930.1759190400000"},
+		{"Cadence Release: 20260215.1771111111111\nCadence Display Name: Stable 2026.01", "Stable 2026.01",
+			"20260215.1771111111111"},
+		// Whatever else checksums.txt holds, it is an error, not a panic.
+		{"", "", ""},
+		{"0123456789abcdef  sas-bases/README.md\n", "", ""},
+		{"Cadence Display Name: Stable 2026.01\n", "", ""},
+		{"Cadence Release: 20260215.1771111111111\n", "", ""},
+		{"Cadence Display Name:\nCadence Release:\n", "", ""},
+		{"Cadence Display Name: Stable 2026.01\nCadence Release:", "", ""},
+	} {
+		cadence, release, err := readCadence(bytes.NewReader(tarballWith(t, tc.checksums, 0)), "deployment assets")
+		if tc.cadence == "" && err == nil {
+			t.Errorf("readCadence of checksums.txt %q returned %q %q, want an error", tc.checksums, cadence, release)
+		}
+		if cadence != tc.cadence || release != tc.release {
+			t.Errorf("readCadence of checksums.txt %q returned %q %q (%v), want %q %q", tc.checksums, cadence,
+				release, err, tc.cadence, tc.release)
+		}
+	}
+}
+
+func TestReadCadenceTooLarge(t *testing.T) {
+	// A header can claim any size, whatever follows it.
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	err := tar.NewWriter(gw).WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: checksumsFile, Mode: 0644,
+		Size: 1 << 40})
+	if err == nil {
+		err = gw.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = readCadence(&buf, "deployment assets"); err == nil {
+		t.Error("readCadence of a checksums.txt of 1 TiB succeeded")
+	}
+}
+
+func TestSavedAssetsSkipsUnreadableFiles(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range map[string][]byte{
+		"SASViyaV4_923457_0_stable_2026.01_20260215.1771111111111_deploymentAssets_1.tgz": tarball(t,
+			"20260215.1771111111111", 0),
+		"SASViyaV4_923457_partial_deploymentAssets.tgz": tarballWith(t, "no labels here\n", 0),
+		"SASViyaV4_923457_stray_deploymentAssets.tgz":   []byte("not deployment assets"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ar := New("apim", "", "id", "secret", "deploymentAssets", "923457", "stable", "", "", dir, "", "json", false)
+	file, release, err := ar.savedAssets()
+	if err != nil || release != "20260215.1771111111111" || filepath.Base(file) !=
+		"SASViyaV4_923457_0_stable_2026.01_20260215.1771111111111_deploymentAssets_1.tgz" {
+		t.Errorf("savedAssets returned %s %q (%v), want the deployment assets that can be read", file, release, err)
+	}
+}
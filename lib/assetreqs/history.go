@@ -0,0 +1,92 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// AssetHistory is the list of completed asset downloads for an order, as returned by the assetHistory endpoint.
+type AssetHistory struct {
+	OrderNumber string          `json:"orderNumber" yaml:"orderNumber"`
+	Downloads   []AssetDownload `json:"assetDownloads" yaml:"assetDownloads"`
+}
+
+// AssetDownload describes one completed asset download.
+type AssetDownload struct {
+	DownloadDate   time.Time `json:"downloadDate" yaml:"downloadDate"`
+	AssetType      string    `json:"assetType" yaml:"assetType"`
+	CadenceName    string    `json:"cadenceName" yaml:"cadenceName"`
+	CadenceVersion string    `json:"cadenceVersion" yaml:"cadenceVersion"`
+	CadenceRelease string    `json:"cadenceRelease" yaml:"cadenceRelease"`
+	User           string    `json:"user" yaml:"user"`
+}
+
+// HistoryFilter selects asset downloads from an AssetHistory. Empty fields match every download.
+type HistoryFilter struct {
+	Since   time.Time // only downloads at or after this time
+	Asset   string    // only downloads of this asset type
+	Cadence string    // only downloads at this cadence name
+}
+
+// ParseAssetHistory decodes an assetHistory response.
+func ParseAssetHistory(r io.Reader) (h AssetHistory, err error) {
+	err = json.NewDecoder(r).Decode(&h)
+	if err != nil {
+		return h, errors.New("ERROR: attempt to parse asset history failed: " + err.Error())
+	}
+	return h, nil
+}
+
+// Filter returns the asset history with only those downloads that match the given filter.
+func (h AssetHistory) Filter(f HistoryFilter) AssetHistory {
+	filtered := AssetHistory{OrderNumber: h.OrderNumber, Downloads: []AssetDownload{}}
+	for _, d := range h.Downloads {
+		if !f.Since.IsZero() && d.DownloadDate.Before(f.Since) {
+			continue
+		}
+		if f.Asset != "" && !strings.EqualFold(d.AssetType, f.Asset) {
+			continue
+		}
+		if f.Cadence != "" && !strings.EqualFold(d.CadenceName, f.Cadence) {
+			continue
+		}
+		filtered.Downloads = append(filtered.Downloads, d)
+	}
+	return filtered
+}
+
+// printHistory reads the asset history saved in the given file and prints the downloads that match the history
+// filter of the AssetReq receiver, in the format specified by the caller.
+func (ar AssetReq) printHistory(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return errors.New("ERROR: attempt to open " + file + " failed: " + err.Error())
+	}
+	defer f.Close()
+
+	h, err := ParseAssetHistory(f)
+	if err != nil {
+		return err
+	}
+	h = h.Filter(*ar.histFilter)
+
+	if oFmt := strings.ToLower(ar.oFmt); oFmt != "" && oFmt != "text" && oFmt != "t" {
+		return Print(ar.infoWriter(), ar.oFmt, &h, h.Downloads)
+	}
+	tw := tabwriter.NewWriter(ar.infoWriter(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOWNLOAD DATE\tASSET TYPE\tCADENCE\tRELEASE\tUSER")
+	for _, d := range h.Downloads {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", d.DownloadDate.Local().Format("2006-01-02 15:04:05"),
+			d.AssetType, strings.TrimSpace(d.CadenceName+" "+d.CadenceVersion), d.CadenceRelease, d.User)
+	}
+	return tw.Flush()
+}
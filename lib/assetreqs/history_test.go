@@ -0,0 +1,154 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+)
+
+// historyJSON is an assetHistory response, as the SAS Viya Orders API sends it.
+const historyJSON = `{
+	"orderNumber": "923457",
+	"assetDownloads": [
+		{"downloadDate": "2026-01-05T14:03:11Z", "assetType": "deploymentAssets", "cadenceName": "stable",
+			"cadenceVersion": "2025.12", "cadenceRelease": "20260105.1767600000000", "user": "jdoe"},
+		{"downloadDate": "2026-01-05T14:03:30Z", "assetType": "license", "cadenceName": "stable",
+			"cadenceVersion": "2025.12", "cadenceRelease": "", "user": "jdoe"},
+		{"downloadDate": "2026-02-02T08:00:00+01:00", "assetType": "certificates", "cadenceName": "",
+			"cadenceVersion": "", "cadenceRelease": "", "user": "automation"},
+		{"downloadDate": "2026-02-15T12:00:00Z", "assetType": "deploymentAssets", "cadenceName": "lts",
+			"cadenceVersion": "2025.09", "cadenceRelease": "20250930.1759190400000", "user": "automation"}
+	]
+}`
+
+func TestParseAssetHistory(t *testing.T) {
+	h, err := assetreqs.ParseAssetHistory(strings.NewReader(historyJSON))
+	if err != nil {
+		t.Fatalf("ParseAssetHistory returned %v", err)
+	}
+	if h.OrderNumber != orderNum || len(h.Downloads) != 4 {
+		t.Fatalf("ParseAssetHistory returned %+v", h)
+	}
+	want := assetreqs.AssetDownload{DownloadDate: time.Date(2026, 1, 5, 14, 3, 11, 0, time.UTC),
+		AssetType: "deploymentAssets", CadenceName: "stable", CadenceVersion: "2025.12",
+		CadenceRelease: "20260105.1767600000000", User: "jdoe"}
+	if d := h.Downloads[0]; !d.DownloadDate.Equal(want.DownloadDate) || d.AssetType != want.AssetType ||
+		d.CadenceName != want.CadenceName || d.CadenceVersion != want.CadenceVersion ||
+		d.CadenceRelease != want.CadenceRelease || d.User != want.User {
+		t.Errorf("ParseAssetHistory returned the download %+v, want %+v", d, want)
+	}
+	// Download dates keep the offset that they were sent with.
+	if d := h.Downloads[2].DownloadDate; !d.Equal(time.Date(2026, 2, 2, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseAssetHistory returned the download date %v", d)
+	}
+
+	// An order without downloads has an empty history.
+	if h, err = assetreqs.ParseAssetHistory(strings.NewReader(`{"orderNumber": "923457"}`)); err != nil ||
+		len(h.Downloads) != 0 {
+		t.Errorf("ParseAssetHistory of an order without downloads returned %+v, %v", h, err)
+	}
+
+	for _, body := range []string{
+		"",
+		"<html><body>Service Unavailable</body></html>",
+		`{"orderNumber": "923457", "assetDownloads": {}}`,
+		`{"orderNumber": "923457", "assetDownloads": [{"downloadDate": "yesterday"}]}`,
+		`{"orderNumber": "923457", "assetDownloads": [`,
+	} {
+		if _, err = assetreqs.ParseAssetHistory(strings.NewReader(body)); err == nil {
+			t.Errorf("ParseAssetHistory accepted %q", body)
+		}
+	}
+}
+
+func TestHistoryFilter(t *testing.T) {
+	h, err := assetreqs.ParseAssetHistory(strings.NewReader(historyJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name   string
+		filter assetreqs.HistoryFilter
+		want   []int // the indexes of the downloads that match
+	}{
+		{name: "no filter", want: []int{0, 1, 2, 3}},
+		{name: "asset", filter: assetreqs.HistoryFilter{Asset: "deploymentAssets"}, want: []int{0, 3}},
+		{name: "asset in another case", filter: assetreqs.HistoryFilter{Asset: "LICENSE"}, want: []int{1}},
+		{name: "cadence", filter: assetreqs.HistoryFilter{Cadence: "Stable"}, want: []int{0, 1}},
+		{name: "since", filter: assetreqs.HistoryFilter{Since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+			want: []int{2, 3}},
+		// Downloads at the time given are included.
+		{name: "since the time of a download",
+			filter: assetreqs.HistoryFilter{Since: time.Date(2026, 2, 2, 7, 0, 0, 0, time.UTC)}, want: []int{2, 3}},
+		{name: "since in another time zone", filter: assetreqs.HistoryFilter{
+			Since: time.Date(2026, 2, 15, 7, 0, 1, 0, time.FixedZone("EST", -5*60*60))}, want: []int{}},
+		{name: "all of them", filter: assetreqs.HistoryFilter{Asset: "deploymentAssets", Cadence: "lts",
+			Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}, want: []int{3}},
+		{name: "nothing matches", filter: assetreqs.HistoryFilter{Asset: "assetHistory"}, want: []int{}},
+	} {
+		f := h.Filter(tc.filter)
+		var got []int
+		for _, d := range f.Downloads {
+			got = append(got, slices.IndexFunc(h.Downloads, func(o assetreqs.AssetDownload) bool {
+				return o.DownloadDate.Equal(d.DownloadDate)
+			}))
+		}
+		if f.OrderNumber != orderNum || f.Downloads == nil || !slices.Equal(got, tc.want) {
+			t.Errorf("%s: Filter returned the downloads %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestGetAssetHistoryFiltered(t *testing.T) {
+	startAPI(t)
+	// The mock records the downloads that it serves.
+	for _, ar := range []assetreqs.AssetReq{newReq("license", "stable", "2026.01", "", t.TempDir()),
+		newReq("certificates", "", "", "", t.TempDir())} {
+		if _, err := ar.Fetch(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		oFmt string
+		want []string // the lines that are printed, apart from the download date
+	}{
+		{oFmt: "csv", want: []string{"downloadDate,assetType,cadenceName,cadenceVersion,cadenceRelease,user",
+			",license,stable,2026.01,,orderstest"}},
+		{oFmt: "go-template={{range .assetDownloads}}{{.assetType}}{{\"\\n\"}}{{end}}", want: []string{"license"}},
+		{oFmt: "text", want: []string{"DOWNLOAD DATE        ASSET TYPE  CADENCE         RELEASE  USER",
+			"  license     stable 2026.01           orderstest"}},
+	} {
+		ar := assetreqs.New("apim", "", "id", "secret", "assetHistory", orderNum, "", "", "", t.TempDir(), "",
+			tc.oFmt, false).WithHistoryFilter(assetreqs.HistoryFilter{Asset: "license",
+			Since: time.Now().Add(-time.Hour)})
+		printed, err := captureStdout(t, ar.GetAsset)
+		if err != nil {
+			t.Errorf("asset history in %s returned %v", tc.oFmt, err)
+			continue
+		}
+		lines := strings.Split(strings.TrimSuffix(printed, "\n"), "\n")
+		if len(lines) != len(tc.want) {
+			t.Errorf("asset history in %s printed\n%s", tc.oFmt, printed)
+			continue
+		}
+		for i, line := range lines {
+			// The download date is the only thing that differs between runs.
+			if i > 0 && tc.oFmt == "csv" {
+				_, line, _ = strings.Cut(line, ",")
+				line = "," + line
+			} else if i > 0 && tc.oFmt == "text" {
+				line = line[len("2006-01-02 15:04:05"):]
+			}
+			if line != tc.want[i] {
+				t.Errorf("asset history in %s printed the line %q, want %q", tc.oFmt, line, tc.want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// CheckOutputFormat returns an error if the given output format is not one that AssetReq can print.
+func CheckOutputFormat(oFmt string) error {
+	switch strings.ToLower(oFmt) {
+	case "text", "t", "json", "j", "yaml", "y", "csv":
+		return nil
+	}
+	if strings.HasPrefix(strings.ToLower(oFmt), "go-template=") || strings.HasPrefix(strings.ToLower(oFmt), "jsonpath=") {
+		_, err := parseTemplate(oFmt)
+		return err
+	}
+	return errors.New("ERROR: invalid output format " + oFmt)
+}
+
+// Print prints the given value in the given output format, which is not text, for commands that print something other
+// than the output of an asset request. For csv, rows is printed instead: a struct, or a slice of structs.
+func Print(w io.Writer, oFmt string, v, rows any) error {
+	switch lFmt := strings.ToLower(oFmt); {
+	case lFmt == "json" || lFmt == "j":
+		b, err := json.MarshalIndent(v, "", "\t")
+		if err != nil {
+			return errors.New("ERROR: json.MarshalIndent() returned: " + err.Error())
+		}
+		_, err = w.Write(append(b, '\n'))
+		if err != nil {
+			return errors.New("ERROR: attempt to write JSON output failed: " + err.Error())
+		}
+		return nil
+	case lFmt == "yaml" || lFmt == "y":
+		return printYAML(w, v)
+	case lFmt == "csv":
+		return printCSV(w, rows)
+	case strings.HasPrefix(lFmt, "go-template=") || strings.HasPrefix(lFmt, "jsonpath="):
+		// Use the format as given since the template itself may be case sensitive.
+		return printTemplate(w, oFmt, v)
+	}
+	return errors.New("ERROR: invalid output format " + oFmt)
+}
+
+// printYAML prints the given struct as YAML.
+func printYAML(w io.Writer, v any) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return errors.New("ERROR: yaml.Marshal() returned: " + err.Error())
+	}
+	_, err = w.Write(b)
+	if err != nil {
+		return errors.New("ERROR: attempt to write YAML output failed: " + err.Error())
+	}
+	return nil
+}
+
+// printCSV prints the given struct, or slice of structs, as a CSV header row, named by the JSON field names, followed by
+// a row of values for each struct.
+func printCSV(w io.Writer, v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	var rows []reflect.Value
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			rows = append(rows, rv.Index(i))
+		}
+	} else {
+		rows = append(rows, rv)
+	}
+
+	typeOfT := rv.Type()
+	if rv.Kind() == reflect.Slice {
+		typeOfT = typeOfT.Elem()
+	}
+	// The fields of embedded structs are columns of their own, as they are fields of their own in JSON.
+	var fields []reflect.StructField
+	var header []string
+	for _, f := range reflect.VisibleFields(typeOfT) {
+		if f.Anonymous || !f.IsExported() {
+			continue
+		}
+		fields = append(fields, f)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		header = append(header, name)
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(header)
+	for _, s := range rows {
+		var row []string
+		for _, f := range fields {
+			field := s.FieldByIndex(f.Index)
+			if t, ok := field.Interface().(time.Time); ok {
+				row = append(row, t.Format(time.RFC3339))
+			} else if l, ok := field.Interface().([]string); ok {
+				row = append(row, strings.Join(l, "; "))
+			} else if m, ok := field.Interface().(map[string]string); ok {
+				// Such as the headers of a request, as name=value pairs in name order.
+				var pairs []string
+				for _, k := range slices.Sorted(maps.Keys(m)) {
+					pairs = append(pairs, k+"="+m[k])
+				}
+				row = append(row, strings.Join(pairs, "; "))
+			} else {
+				row = append(row, fmt.Sprintf("%v", field.Interface()))
+			}
+		}
+		_ = cw.Write(row)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return errors.New("ERROR: attempt to write CSV output failed: " + err.Error())
+	}
+	return nil
+}
+
+// printTemplate executes the go-template= or jsonpath= template in the given output format against the given struct.
+// As with kubectl, the template refers to fields by their JSON names.
+func printTemplate(w io.Writer, oFmt string, v any) error {
+	tmpl, err := parseTemplate(oFmt)
+	if err != nil {
+		return err
+	}
+
+	// Round trip through JSON so that the template sees the JSON field names.
+	b, err := json.Marshal(v)
+	if err != nil {
+		return errors.New("ERROR: json.Marshal() returned: " + err.Error())
+	}
+	// A list, such as that of the downloaded assets, is ranged over by the template.
+	var data any
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		return errors.New("ERROR: json.Unmarshal() returned: " + err.Error())
+	}
+
+	err = tmpl.Execute(w, data)
+	if err != nil {
+		return errors.New("ERROR: attempt to execute output template failed: " + err.Error())
+	}
+	return nil
+}
+
+// parseTemplate parses the template in a go-template= or jsonpath= output format.
+func parseTemplate(oFmt string) (*template.Template, error) {
+	kind, text, _ := strings.Cut(oFmt, "=")
+	if strings.EqualFold(kind, "jsonpath") {
+		var err error
+		text, err = jsonpathToTemplate(text)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if text == "" {
+		return nil, errors.New("ERROR: no template given in output format " + oFmt)
+	}
+
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to parse output template failed: " + err.Error())
+	}
+	return tmpl, nil
+}
+
+// jsonpathToTemplate converts a kubectl style JSONPath template into a Go template. Only the subset of JSONPath that
+// applies to the flat output struct is supported: field references such as {.cadenceRelease}, the root object {$} or
+// {.}, and quoted literals such as {"\n"}. Text outside of braces is printed as is.
+func jsonpathToTemplate(jp string) (string, error) {
+	var b strings.Builder
+	for jp != "" {
+		st := strings.Index(jp, "{")
+		if st < 0 {
+			b.WriteString(templateLiteral(jp))
+			break
+		}
+		b.WriteString(templateLiteral(jp[:st]))
+		end := strings.Index(jp[st:], "}")
+		if end < 0 {
+			return "", errors.New("ERROR: unclosed { in JSONPath template " + jp)
+		}
+		expr := strings.TrimSpace(jp[st+1 : st+end])
+		jp = jp[st+end+1:]
+
+		switch {
+		case strings.HasPrefix(expr, `"`):
+			b.WriteString("{{" + expr + "}}")
+		case expr == "$" || expr == ".":
+			b.WriteString("{{.}}")
+		case strings.HasPrefix(expr, "$."):
+			b.WriteString("{{" + expr[1:] + "}}")
+		case strings.HasPrefix(expr, ".") && !strings.ContainsAny(expr, "[]()*?@ ") && !strings.Contains(expr, ".."):
+			b.WriteString("{{" + expr + "}}")
+		default:
+			return "", errors.New("ERROR: unsupported JSONPath expression {" + expr + "}")
+		}
+	}
+	return b.String(), nil
+}
+
+// templateLiteral quotes the given text so that a Go template prints it as is.
+func templateLiteral(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+	return "{{" + fmt.Sprintf("%q", s) + "}}"
+}
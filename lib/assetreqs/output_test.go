@@ -0,0 +1,184 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/provenance"
+)
+
+func TestPrint(t *testing.T) {
+	downloaded := time.Date(2026, 2, 15, 12, 0, 0, 0, time.UTC)
+	assets := []provenance.Asset{
+		{Metadata: provenance.Metadata{OrderNumber: "923457", AssetName: "license", AssetFile: "license.jwt",
+			Cadence: "Stable 2026.01", DownloadedAt: downloaded, Size: 42, SHA256: "abc"}, Status: "ok"},
+		{Metadata: provenance.Metadata{OrderNumber: "923457", AssetName: "certificates", AssetFile: "certs.zip",
+			DownloadedAt: downloaded, Size: 7, SHA256: "def"}, Status: "missing"},
+	}
+	for _, tc := range []struct {
+		oFmt string
+		want string
+	}{
+		{oFmt: "yaml", want: "- orderNumber: \"923457\"\n  assetName: license\n  assetFile: license.jwt\n  assetReqURL: \"\"\n" +
+			"  cadence: Stable 2026.01\n  downloadedAt: 2026-02-15T12:00:00Z\n  size: 42\n  sha256: abc\n" +
+			"  cliVersion: \"\"\n  status: ok\n- orderNumber: \"923457\"\n  assetName: certificates\n" +
+			"  assetFile: certs.zip\n  assetReqURL: \"\"\n  downloadedAt: 2026-02-15T12:00:00Z\n  size: 7\n" +
+			"  sha256: def\n  cliVersion: \"\"\n  status: missing\n"},
+		// The fields of the embedded metadata are columns of their own.
+		{oFmt: "csv", want: "orderNumber,assetName,assetFile,assetReqURL,cadence,cadenceRelease,downloadedAt,size," +
+			"sha256,cliVersion,status\n" +
+			"923457,license,license.jwt,,Stable 2026.01,,2026-02-15T12:00:00Z,42,abc,,ok\n" +
+			"923457,certificates,certs.zip,,,,2026-02-15T12:00:00Z,7,def,,missing\n"},
+		{oFmt: `go-template={{range .}}{{.assetFile}} {{.status}}{{"\n"}}{{end}}`,
+			want: "license.jwt ok\ncerts.zip missing\n"},
+		{oFmt: `JSONPath={.}`, want: "[map[assetFile:license.jwt assetName:license assetReqURL: " +
+			"cadence:Stable 2026.01 cliVersion: downloadedAt:2026-02-15T12:00:00Z orderNumber:923457 sha256:abc " +
+			"size:42 status:ok] map[assetFile:certs.zip assetName:certificates assetReqURL: cliVersion: " +
+			"downloadedAt:2026-02-15T12:00:00Z orderNumber:923457 sha256:def size:7 status:missing]]"},
+	} {
+		var buf bytes.Buffer
+		if err := Print(&buf, tc.oFmt, assets, assets); err != nil {
+			t.Errorf("Print in %s returned %v", tc.oFmt, err)
+		} else if buf.String() != tc.want {
+			t.Errorf("Print in %s printed\n%s\nwant\n%s", tc.oFmt, buf.String(), tc.want)
+		}
+	}
+
+	// The JSON is that of the value, followed by a newline; the CSV is that of the rows.
+	var buf bytes.Buffer
+	pruned := struct {
+		Removed    []provenance.Asset `json:"removed"`
+		FreedBytes int64              `json:"freedBytes"`
+	}{assets[1:], 7}
+	if err := Print(&buf, "j", pruned, pruned.Removed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("{\n\t\"removed\": [\n")) || !bytes.HasSuffix(buf.Bytes(), []byte("}\n")) {
+		t.Errorf("Print in JSON printed %s", buf.String())
+	}
+	buf.Reset()
+	if err := Print(&buf, "csv", pruned, pruned.Removed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("orderNumber,")) || bytes.Count(buf.Bytes(), []byte("\n")) != 2 {
+		t.Errorf("Print in CSV printed %s", buf.String())
+	}
+
+	if err := Print(&buf, "xml", assets, assets); err == nil {
+		t.Error("Print accepted the output format xml")
+	}
+}
+
+func TestJsonpathToTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		jp, tmpl string
+		err      bool
+	}{
+		{jp: "{.cadenceRelease}", tmpl: "{{.cadenceRelease}}"},
+		{jp: `{.orderNumber}{"\t"}{.assetLocation}{"\n"}`, tmpl: `{{.orderNumber}}{{"\t"}}{{.assetLocation}}{{"\n"}}`},
+		{jp: "{$}", tmpl: "{{.}}"},
+		{jp: "{.}", tmpl: "{{.}}"},
+		{jp: "{$.cadence}", tmpl: "{{.cadence}}"},
+		{jp: "{ .cadence }", tmpl: "{{.cadence}}"},
+		{jp: "release: {.cadenceRelease}!", tmpl: "release: {{.cadenceRelease}}!"},
+		{jp: "no fields", tmpl: "no fields"},
+		{jp: "{{.cadence}}", err: true},
+		{jp: "{.cadence", err: true},
+		{jp: "{.items[0]}", err: true},
+		{jp: "{.items[*].name}", err: true},
+		{jp: "{..name}", err: true},
+		{jp: "{@.name}", err: true},
+		{jp: "{cadence}", err: true},
+	} {
+		tmpl, err := jsonpathToTemplate(tc.jp)
+		if (err != nil) != tc.err || tmpl != tc.tmpl {
+			t.Errorf("jsonpathToTemplate(%q) returned %q, %v, want %q and error %t", tc.jp, tmpl, err, tc.tmpl, tc.err)
+		}
+	}
+}
+
+func TestPrintCSV(t *testing.T) {
+	type row struct {
+		Name    string            `json:"name"`
+		Size    int64             `json:"size,omitempty"`
+		When    time.Time         `json:"when"`
+		Tags    []string          `json:"tags"`
+		Headers map[string]string `json:"headers"`
+		secret  string
+	}
+	when := time.Date(2026, 2, 15, 12, 0, 0, 0, time.FixedZone("EST", -5*60*60))
+	for _, tc := range []struct {
+		name string
+		v    any
+		want string
+	}{
+		{name: "struct", v: row{Name: "license", Size: 42, When: when, secret: "x"},
+			want: "name,size,when,tags,headers\nlicense,42,2026-02-15T12:00:00-05:00,,\n"},
+		{name: "pointer", v: &row{Name: "license"}, want: "name,size,when,tags,headers\nlicense,0,0001-01-01T00:00:00Z,,\n"},
+		{name: "empty slice", v: []row{}, want: "name,size,when,tags,headers\n"},
+		// Values are quoted as CSV needs them to be.
+		{name: "quoting", v: []row{
+			{Name: "Stable 2026.01, preview", When: when},
+			{Name: `the "latest" release`, When: when},
+			{Name: "two\nlines", When: when, Tags: []string{"a", "b,c"}},
+			{Name: "headers", When: when, Headers: map[string]string{"Content-Type": "application/zip", "Accept": "*/*"}},
+		}, want: "name,size,when,tags,headers\n" +
+			`"Stable 2026.01, preview",0,2026-02-15T12:00:00-05:00,,` + "\n" +
+			`"the ""latest"" release",0,2026-02-15T12:00:00-05:00,,` + "\n" +
+			"\"two\nlines\",0,2026-02-15T12:00:00-05:00,\"a; b,c\",\n" +
+			"headers,0,2026-02-15T12:00:00-05:00,,Accept=*/*; Content-Type=application/zip\n"},
+	} {
+		var buf bytes.Buffer
+		if err := printCSV(&buf, tc.v); err != nil {
+			t.Errorf("%s: printCSV returned %v", tc.name, err)
+		} else if buf.String() != tc.want {
+			t.Errorf("%s: printCSV printed\n%s\nwant\n%s", tc.name, buf.String(), tc.want)
+		}
+	}
+}
+
+func TestPrintTemplate(t *testing.T) {
+	output := Output{OrderNumber: "923457", AssetName: "deploymentAssets", Cadence: "Stable 2026.01",
+		CadenceRelease: "20260215.1771111111111", AssetLocation: "/tmp/SASViyaV4_923457_0_stable-2026.01.tgz"}
+	for _, tc := range []struct {
+		oFmt, want string
+		err        bool
+	}{
+		{oFmt: "go-template={{.cadenceRelease}}", want: "20260215.1771111111111"},
+		{oFmt: `go-template={{.orderNumber}} {{if eq .assetLocation "-"}}streamed{{else}}saved{{end}}`,
+			want: "923457 saved"},
+		// Fields that are left out of the JSON, as the upload location is when nothing was uploaded, are missing.
+		{oFmt: "go-template={{.uploadLocation}}", err: true},
+		{oFmt: `JSONPATH={.assetName} {.cadence}{"\n"}`, want: "deploymentAssets Stable 2026.01\n"},
+		{oFmt: "jsonpath={$.assetLocation}", want: "/tmp/SASViyaV4_923457_0_stable-2026.01.tgz"},
+		// Fields are referred to by their JSON names, which are case sensitive.
+		{oFmt: "go-template={{.CadenceRelease}}", err: true},
+		{oFmt: "jsonpath={.release}", err: true},
+		{oFmt: "go-template={{.cadence", err: true},
+		{oFmt: "go-template={{range}}", err: true},
+		{oFmt: "go-template=", err: true},
+		{oFmt: "jsonpath=", err: true},
+		{oFmt: "jsonpath={.cadence", err: true},
+	} {
+		var buf bytes.Buffer
+		err := printTemplate(&buf, tc.oFmt, output)
+		if (err != nil) != tc.err || (!tc.err && buf.String() != tc.want) {
+			t.Errorf("printTemplate in %s printed %q, %v, want %q and error %t", tc.oFmt, buf.String(), err, tc.want,
+				tc.err)
+		}
+		// Templates that cannot be parsed are caught before the asset is requested.
+		if checkErr := CheckOutputFormat(tc.oFmt); checkErr != nil && !tc.err {
+			t.Errorf("CheckOutputFormat(%q) returned %v", tc.oFmt, checkErr)
+		}
+	}
+
+	for _, oFmt := range []string{"go-template={{.cadence", "go-template=", "jsonpath={.items[0]}", "xml", "table"} {
+		if err := CheckOutputFormat(oFmt); err == nil {
+			t.Errorf("CheckOutputFormat accepted %q", oFmt)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assetreqs
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// How often progress is reported: redrawn on a terminal, or logged otherwise.
+const (
+	ttyProgressInterval time.Duration = 200 * time.Millisecond
+	logProgressInterval time.Duration = 10 * time.Second
+)
+
+// progressReader counts the bytes of an asset as they are read and, if asked to, reports how the download is going
+// on STDERR: as a line that is redrawn if STDERR is a terminal, or as a log record every so often if it is not.
+type progressReader struct {
+	r      io.Reader
+	order  string
+	asset  string
+	total  int64 // -1 if not known
+	n      int64
+	start  time.Time
+	report bool
+	tty    bool
+	last   time.Time
+	drawn  bool
+}
+
+// newProgressReader returns a progressReader for the given asset, of the given total size, read from r.
+func newProgressReader(r io.Reader, order, asset string, total int64, report bool) *progressReader {
+	now := time.Now()
+	return &progressReader{
+		r:      r,
+		order:  order,
+		asset:  asset,
+		total:  total,
+		start:  now,
+		report: report,
+		tty:    report && term.IsTerminal(int(os.Stderr.Fd())),
+		last:   now,
+	}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if p.report {
+		interval := logProgressInterval
+		if p.tty {
+			interval = ttyProgressInterval
+		}
+		if now := time.Now(); now.Sub(p.last) >= interval {
+			p.last = now
+			p.show()
+		}
+	}
+	return n, err
+}
+
+// rate returns the average number of bytes read per second so far.
+func (p *progressReader) rate() float64 {
+	secs := time.Since(p.start).Seconds()
+	if secs <= 0 {
+		return 0
+	}
+	return float64(p.n) / secs
+}
+
+// eta returns how long the rest of the asset should take to read at the average rate so far, or 0 if that is not
+// known.
+func (p *progressReader) eta() time.Duration {
+	rate := p.rate()
+	if p.total <= 0 || rate <= 0 || p.n >= p.total {
+		return 0
+	}
+	return time.Duration(float64(p.total-p.n) / rate * float64(time.Second)).Round(time.Second)
+}
+
+// show reports the progress so far.
+func (p *progressReader) show() {
+	if !p.tty {
+		attrs := []any{"order", p.order, "asset", p.asset, "bytes", p.n}
+		if p.total > 0 {
+			attrs = append(attrs, "total", p.total, "eta", p.eta())
+		}
+		slog.Info("download progress", append(attrs, "bytesPerSecond", int64(p.rate()))...)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(p.asset + ": " + formatBytes(p.n))
+	if p.total > 0 {
+		fmt.Fprintf(&b, " / %s (%d%%)", formatBytes(p.total), p.n*100/p.total)
+	}
+	b.WriteString(", " + formatBytes(int64(p.rate())) + "/s")
+	if eta := p.eta(); eta > 0 {
+		b.WriteString(", " + eta.String() + " left")
+	}
+	// Return to the start of the line and clear it before drawing the new one.
+	fmt.Fprint(os.Stderr, "\r\033[K"+b.String())
+	p.drawn = true
+}
+
+// finish clears the progress line from the terminal, if one was drawn.
+func (p *progressReader) finish() {
+	if p.drawn {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+// formatBytes returns the given number of bytes in binary units, such as 1.5 MiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
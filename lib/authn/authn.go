@@ -1,7 +1,7 @@
 // Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
-// Package authn provides a func that will exchange OAuth client credentials for a Bearer token that will expire after
+// Package authn provides funcs that will exchange OAuth client credentials for Bearer tokens that will expire after
 // 30 minutes.
 package authn
 
@@ -9,9 +9,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/apierrors"
+	"github.com/sassoftware/viya4-orders-cli/lib/metrics"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
@@ -22,13 +27,84 @@ const (
 	viyaOrdersAPITokenPath string = "/token"
 )
 
+// apiHost is the SAS Viya Orders API host that Bearer token requests go to instead of the usual one, if set.
+var apiHost string
+
+// SetAPIHost makes Bearer token requests go to the given SAS Viya Orders API host, such as a mock of the API.
+func SetAPIHost(host string) {
+	apiHost = host
+}
+
 // GetBearerToken calls the /token SAS Viya Orders API endpoint to exchange client credentials for a Bearer token to
 // use with the Apigee proxy.
 func GetBearerToken(cID, cSec string) (token string, err error) {
+	oauthCfg, err := tokenConfig(cID, cSec)
+	if err != nil {
+		return token, err
+	}
+
+	oaToken, err := requestToken(oauthCfg)
+	if err != nil {
+		return token, err
+	}
+	token = oaToken.AccessToken
+
+	return token, nil
+}
+
+// TokenSource returns a source of Bearer tokens for the given client credentials, for processes that run for longer
+// than a token lasts. It reuses each token until it is about to expire, and then requests a new one.
+func TokenSource(cID, cSec string) (oauth2.TokenSource, error) {
+	oauthCfg, err := tokenConfig(cID, cSec)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.ReuseTokenSource(nil, countingSource{oauthCfg}), nil
+}
+
+// countingSource requests a new Bearer token every time that it is asked for one, and counts the requests.
+type countingSource struct {
+	cfg *clientcredentials.Config
+}
+
+func (cs countingSource) Token() (*oauth2.Token, error) {
+	return requestToken(cs.cfg)
+}
+
+// requestToken requests a new Bearer token with the given configuration.
+func requestToken(cfg *clientcredentials.Config) (*oauth2.Token, error) {
+	slog.Debug("requesting Bearer token", "url", cfg.TokenURL)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, apiclient.Client())
+	start := time.Now()
+	t, err := cfg.Token(ctx)
+	metrics.TokenRequested(err)
+	if err != nil {
+		return nil, tokenError(cfg.TokenURL, err)
+	}
+	slog.Debug("received Bearer token", "url", cfg.TokenURL, "expiry", t.Expiry,
+		"duration", time.Since(start).Round(time.Millisecond))
+	return t, nil
+}
+
+// tokenError returns the error for a Bearer token request to the given URL that failed with the given error.
+func tokenError(tokenURL string, err error) error {
+	var re *oauth2.RetrieveError
+	if errors.As(err, &re) && re.Response != nil {
+		return apierrors.New("Bearer token request", tokenURL, re.Response.StatusCode, re.Response.Header, re.Body)
+	}
+	return apierrors.Network("Bearer token request", tokenURL, err)
+}
+
+// tokenConfig returns the OAuth client credentials configuration for the /token SAS Viya Orders API endpoint.
+func tokenConfig(cID, cSec string) (*clientcredentials.Config, error) {
 	// Build the request URL.
-	u, err := url.ParseRequestURI(viyaOrdersAPIHost)
+	host := viyaOrdersAPIHost
+	if apiHost != "" {
+		host = apiHost
+	}
+	u, err := url.ParseRequestURI(host)
 	if err != nil {
-		return token, errors.New("ERROR: attempt to parse Bearer token request URI failed: " + err.Error())
+		return nil, errors.New("ERROR: attempt to parse Bearer token request URI failed: " + err.Error())
 	}
 
 	var b strings.Builder
@@ -37,18 +113,10 @@ func GetBearerToken(cID, cSec string) (token string, err error) {
 	u.Path = b.String()
 	urlStr := u.String()
 
-	oauthCfg := &clientcredentials.Config{
+	return &clientcredentials.Config{
 		ClientID:     cID,
 		ClientSecret: cSec,
 		TokenURL:     urlStr,
 		AuthStyle:    oauth2.AuthStyleAutoDetect,
-	}
-
-	oaToken, err := oauthCfg.Token(context.Background())
-	if err != nil {
-		return token, errors.New("ERROR: Bearer token request failed: " + err.Error())
-	}
-	token = oaToken.AccessToken
-
-	return token, nil
+	}, nil
 }
@@ -0,0 +1,292 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cache provides an on-disk cache of order assets. The contents of each asset are stored once, under their
+// SHA-256 digest, and an entry for each request records which contents it was last satisfied with, along with what
+// is needed to revalidate them with the API.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry describes a cached order asset.
+type Entry struct {
+	Key                string    `json:"key" yaml:"key"` // the URL that the asset was requested from
+	OrderNumber        string    `json:"orderNumber" yaml:"orderNumber"`
+	AssetName          string    `json:"assetName" yaml:"assetName"`
+	Cadence            string    `json:"cadence,omitempty" yaml:"cadence,omitempty"`
+	CadenceRelease     string    `json:"cadenceRelease,omitempty" yaml:"cadenceRelease,omitempty"`
+	ContentDisposition string    `json:"contentDisposition" yaml:"contentDisposition"`
+	ETag               string    `json:"etag,omitempty" yaml:"etag,omitempty"`
+	LastModified       string    `json:"lastModified,omitempty" yaml:"lastModified,omitempty"`
+	Digest             string    `json:"digest" yaml:"digest"` // sha256:<hex digest of the contents>
+	Size               int64     `json:"size" yaml:"size"`
+	Created            time.Time `json:"created" yaml:"created"`
+	LastUsed           time.Time `json:"lastUsed" yaml:"lastUsed"`
+}
+
+// tmpGrace is how long after it was last written to the contents of an asset that is being cached are taken to still
+// be in the process of being written, rather than left over from an interrupted download.
+const tmpGrace = time.Hour
+
+// Cache is a cache in a directory on disk.
+type Cache struct {
+	dir string
+}
+
+// Writer writes the contents of an asset to the cache. The asset is not cached until Commit is called.
+type Writer struct {
+	c     *Cache
+	f     *os.File
+	hash  hash.Hash
+	size  int64
+	entry Entry
+}
+
+// DefaultDir returns the directory that is used when none is given: viya4-orders-cli in the user's cache directory.
+func DefaultDir() (string, error) {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.New("ERROR: attempt to find the user cache directory failed: " + err.Error())
+	}
+	return filepath.Join(d, "viya4-orders-cli"), nil
+}
+
+// New returns the Cache in the given directory, or in the default directory if none is given.
+func New(dir string) (*Cache, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Cache{dir: dir}, nil
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Lookup returns the entry for the given key, or nil if there is no entry or its contents are gone.
+func (c *Cache) Lookup(key string) (*Entry, error) {
+	e, err := readEntry(c.entryPath(key))
+	if err != nil || e == nil {
+		return nil, err
+	}
+	if _, err := os.Stat(c.BlobPath(*e)); err != nil {
+		return nil, nil
+	}
+	return e, nil
+}
+
+// Open opens the cached contents of the given entry.
+func (c *Cache) Open(e Entry) (*os.File, error) {
+	f, err := os.Open(c.BlobPath(e))
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to open cached asset failed: " + err.Error())
+	}
+	return f, nil
+}
+
+// BlobPath returns the path of the file that holds the contents of the given entry.
+func (c *Cache) BlobPath(e Entry) string {
+	return filepath.Join(c.dir, "blobs", "sha256", strings.TrimPrefix(e.Digest, "sha256:"))
+}
+
+// Begin starts caching the asset described by the given entry, and returns a writer for its contents.
+func (c *Cache) Begin(e Entry) (*Writer, error) {
+	dir := filepath.Join(c.dir, "blobs", "sha256")
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to create cache directory " + dir + " failed: " + err.Error())
+	}
+	f, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to create file in cache directory " + dir + " failed: " + err.Error())
+	}
+	return &Writer{c: c, f: f, hash: sha256.New(), entry: e}, nil
+}
+
+// Write writes part of the contents of the asset.
+func (w *Writer) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.hash.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+// Commit moves the contents that were written into place and records the entry for them. The contents are only kept
+// once, however many entries refer to them.
+func (w *Writer) Commit() (Entry, error) {
+	e := w.entry
+	e.Digest = "sha256:" + hex.EncodeToString(w.hash.Sum(nil))
+	e.Size = w.size
+	e.Created = time.Now().UTC()
+	e.LastUsed = e.Created
+
+	err := w.f.Close()
+	if err != nil {
+		_ = os.Remove(w.f.Name())
+		return e, errors.New("ERROR: attempt to write cached asset failed: " + err.Error())
+	}
+	err = os.Rename(w.f.Name(), w.c.BlobPath(e))
+	if err != nil {
+		_ = os.Remove(w.f.Name())
+		return e, errors.New("ERROR: attempt to store cached asset failed: " + err.Error())
+	}
+
+	return e, w.c.writeEntry(e)
+}
+
+// Abort discards the contents that were written.
+func (w *Writer) Abort() {
+	_ = w.f.Close()
+	_ = os.Remove(w.f.Name())
+}
+
+// Record sets the cadence information of the entry for the given key, and marks it as used now.
+func (c *Cache) Record(key, cadence, release string) error {
+	e, err := readEntry(c.entryPath(key))
+	if err != nil || e == nil {
+		return err
+	}
+	if cadence != "" {
+		e.Cadence = cadence
+		e.CadenceRelease = release
+	}
+	e.LastUsed = time.Now().UTC()
+	return c.writeEntry(*e)
+}
+
+// List returns the cached entries, most recently used first.
+func (c *Cache) List() ([]Entry, error) {
+	files, err := filepath.Glob(filepath.Join(c.dir, "entries", "*.json"))
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to list cache entries failed: " + err.Error())
+	}
+	entries := []Entry{}
+	for _, f := range files {
+		e, err := readEntry(f)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			entries = append(entries, *e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.After(entries[j].LastUsed) })
+	return entries, nil
+}
+
+// Prune removes the entries that have not been used since the given time (all of them if it is zero), and then the
+// contents that no entry refers to any more. Contents that another download may still be writing are left alone. It
+// returns the entries that were removed and the number of bytes freed.
+func (c *Cache) Prune(before time.Time) (removed []Entry, freed int64, err error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, 0, err
+	}
+	removed = []Entry{}
+	inUse := map[string]bool{}
+	for _, e := range entries {
+		if before.IsZero() || e.LastUsed.Before(before) {
+			err = os.Remove(c.entryPath(e.Key))
+			if err != nil && !os.IsNotExist(err) {
+				return removed, freed, errors.New("ERROR: attempt to remove cache entry failed: " + err.Error())
+			}
+			removed = append(removed, e)
+			continue
+		}
+		inUse[c.BlobPath(e)] = true
+	}
+
+	// Leftovers of interrupted downloads are removed, too.
+	blobs, err := filepath.Glob(filepath.Join(c.dir, "blobs", "sha256", "*"))
+	if err != nil {
+		return removed, freed, errors.New("ERROR: attempt to list cached assets failed: " + err.Error())
+	}
+	for _, b := range blobs {
+		if inUse[b] {
+			continue
+		}
+		fi, err := os.Stat(b)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(filepath.Base(b), ".tmp-") && time.Since(fi.ModTime()) < tmpGrace {
+			continue
+		}
+		err = os.Remove(b)
+		if err != nil {
+			return removed, freed, errors.New("ERROR: attempt to remove cached asset failed: " + err.Error())
+		}
+		freed += fi.Size()
+	}
+
+	return removed, freed, nil
+}
+
+// entryPath returns the path of the file that holds the entry for the given key.
+func (c *Cache) entryPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, "entries", hex.EncodeToString(sum[:])+".json")
+}
+
+// writeEntry saves the given entry.
+func (c *Cache) writeEntry(e Entry) error {
+	dir := filepath.Join(c.dir, "entries")
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return errors.New("ERROR: attempt to create cache directory " + dir + " failed: " + err.Error())
+	}
+	b, err := json.MarshalIndent(e, "", "\t")
+	if err != nil {
+		return errors.New("ERROR: json.MarshalIndent() returned: " + err.Error())
+	}
+	// Write the entry next to where it goes, so that readers never see a partial entry.
+	f, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return errors.New("ERROR: attempt to create file in cache directory " + dir + " failed: " + err.Error())
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.entryPath(e.Key))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return errors.New("ERROR: attempt to write cache entry failed: " + err.Error())
+	}
+	return nil
+}
+
+// readEntry reads the entry in the given file, returning nil if there is no such file.
+func readEntry(file string) (*Entry, error) {
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to read cache entry " + file + " failed: " + err.Error())
+	}
+	var e Entry
+	err = json.Unmarshal(b, &e)
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to parse cache entry " + file + " failed: " + err.Error())
+	}
+	return &e, nil
+}
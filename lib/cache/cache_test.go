@@ -0,0 +1,55 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneLeavesDownloadsInProgress(t *testing.T) {
+	c, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := c.Begin(Entry{Key: "https://example.com/orders/123456/license", OrderNumber: "123456",
+		AssetName: "license"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Abort()
+	if _, err = w.Write([]byte("in progress")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A download that was interrupted long enough ago is removed.
+	stale, err := os.CreateTemp(filepath.Dir(w.f.Name()), ".tmp-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.Close()
+	old := time.Now().Add(-2 * tmpGrace)
+	if err = os.Chtimes(stale.Name(), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = c.Prune(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(w.f.Name()); err != nil {
+		t.Errorf("Prune removed an asset that was being cached: %v", err)
+	}
+	if _, err = os.Stat(stale.Name()); !os.IsNotExist(err) {
+		t.Errorf("Prune left an interrupted download behind: %v", err)
+	}
+
+	if _, err = w.Write([]byte(", and done")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Commit(); err != nil {
+		t.Errorf("Commit after Prune returned %v", err)
+	}
+}
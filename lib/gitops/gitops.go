@@ -0,0 +1,361 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gitops extracts the sas-bases directory of deployment assets into a git working tree and commits it.
+package gitops
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// basesDir is the directory in the deployment assets tarball that holds the assets to extract.
+const basesDir string = "sas-bases"
+
+// markerFile is the file that marks a directory as one that ExtractBases created, and so may replace.
+const markerFile string = ".viya4-orders-cli-sas-bases"
+
+// markerContents explains the marker file to those who come across it.
+const markerContents string = "This directory is replaced with the contents of sas-bases by viya4-orders-cli gitops sync.\n" +
+	"Do not keep anything else in it.\n"
+
+// invalidBranchChars matches the characters that are kept out of branch names.
+var invalidBranchChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// Repo is a local git working tree.
+type Repo struct {
+	dir string // the top-level directory of the working tree
+}
+
+// Open returns the Repo for the git working tree that contains the given directory.
+func Open(dir string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("ERROR: git is required for gitops but was not found: " + err.Error())
+	}
+	top, err := (&Repo{dir: dir}).git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, errors.New("ERROR: " + dir + " is not in a git working tree: " + err.Error())
+	}
+	return &Repo{dir: top}, nil
+}
+
+// Dir returns the top-level directory of the working tree.
+func (r *Repo) Dir() string {
+	return r.dir
+}
+
+// CheckPath returns an error if the given path, relative to the top-level directory of a working tree, is not one that
+// the contents of sas-bases can be extracted to: a directory inside the working tree, other than the top-level
+// directory itself, that is not in the .git directory.
+func CheckPath(relPath string) error {
+	relPath = filepath.Clean(relPath)
+	if relPath == "." {
+		return errors.New("ERROR: the path must be a directory inside the working tree, not its top-level directory")
+	}
+	if !filepath.IsLocal(relPath) {
+		return errors.New("ERROR: the path must be a relative path inside the working tree")
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(relPath), "/") {
+		// The file system may not be case sensitive.
+		if strings.EqualFold(elem, ".git") {
+			return errors.New("ERROR: the path cannot be in a .git directory")
+		}
+	}
+	return nil
+}
+
+// BranchName returns the name of the branch for the given cadence and release: sas-viya/<cadence>-<release>, in
+// lowercase and with spaces replaced by dashes.
+func BranchName(cadence, release string) string {
+	name := invalidBranchChars.ReplaceAllString(strings.ToLower(cadence+"-"+release), "-")
+	return "sas-viya/" + strings.Trim(name, "-.")
+}
+
+// SwitchBranch switches the working tree to the given branch, creating it from the current commit if it does not
+// exist yet.
+func (r *Repo) SwitchBranch(name string) error {
+	if _, err := r.git("rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+		_, err = r.git("switch", "--quiet", name)
+		if err != nil {
+			return errors.New("ERROR: attempt to switch to branch " + name + " failed: " + err.Error())
+		}
+		return nil
+	}
+	_, err := r.git("switch", "--quiet", "--create", name)
+	if err != nil {
+		return errors.New("ERROR: attempt to create branch " + name + " failed: " + err.Error())
+	}
+	return nil
+}
+
+// CurrentBranch returns the name of the branch that is checked out.
+func (r *Repo) CurrentBranch() (string, error) {
+	b, err := r.git("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "", errors.New("ERROR: attempt to find the current branch failed - is HEAD detached? " + err.Error())
+	}
+	return b, nil
+}
+
+// CheckClean returns an error if the given path in the working tree has changes that are not committed, so that they
+// are not overwritten.
+func (r *Repo) CheckClean(relPath string) error {
+	status, err := r.git("status", "--porcelain", "--", relPath)
+	if err != nil {
+		return errors.New("ERROR: attempt to get the status of " + relPath + " failed: " + err.Error())
+	}
+	if status != "" {
+		return errors.New("ERROR: " + relPath + " has changes that are not committed:\n" + status)
+	}
+	return nil
+}
+
+// Commit commits the contents of the given path in the working tree with the given message. It returns the commit
+// that HEAD points to afterwards and whether anything had changed.
+func (r *Repo) Commit(relPath, message string) (commit string, changed bool, err error) {
+	_, err = r.git("add", "--all", "--", relPath)
+	if err != nil {
+		return "", false, errors.New("ERROR: attempt to stage " + relPath + " failed: " + err.Error())
+	}
+
+	// diff --quiet exits with status 1 when there are differences.
+	_, err = r.git("diff", "--cached", "--quiet", "--", relPath)
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		changed = true
+	default:
+		return "", false, errors.New("ERROR: attempt to compare " + relPath + " with HEAD failed: " + err.Error())
+	}
+
+	if changed {
+		// Only commit the given path, even if other changes are staged.
+		_, err = r.git("commit", "--quiet", "--message", message, "--", relPath)
+		if err != nil {
+			return "", true, errors.New("ERROR: attempt to commit " + relPath + " failed: " + err.Error())
+		}
+	}
+
+	commit, err = r.git("rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil && changed {
+		return "", true, errors.New("ERROR: attempt to get the new commit failed: " + err.Error())
+	}
+	return commit, changed, nil
+}
+
+// git runs git in the working tree with the given arguments and returns its trimmed output. The error includes
+// anything that git wrote to stderr.
+func (r *Repo) git(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", r.dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", &gitError{err: err, msg: msg}
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// gitError is a failed git command, along with what it wrote to stderr.
+type gitError struct {
+	err error
+	msg string
+}
+
+func (e *gitError) Error() string {
+	return e.err.Error() + ": " + e.msg
+}
+
+func (e *gitError) Unwrap() error {
+	return e.err
+}
+
+// ExtractBases replaces the contents of the dest directory with the contents of the sas-bases directory in the given
+// deployment assets tarball. The contents are extracted next to dest first, so that dest is left as it was if the
+// tarball cannot be read. Entries that would be written outside of dest are rejected. To make sure that nothing else is
+// replaced, dest must not exist, be empty, or have been created by ExtractBases, which marks the directories it creates.
+func ExtractBases(tarball, dest string) error {
+	err := checkReplaceable(dest)
+	if err != nil {
+		return err
+	}
+
+	parent := filepath.Dir(dest)
+	err = os.MkdirAll(parent, 0755)
+	if err != nil {
+		return errors.New("ERROR: attempt to create " + parent + " failed: " + err.Error())
+	}
+	tmp, err := os.MkdirTemp(parent, "."+filepath.Base(dest)+"-")
+	if err != nil {
+		return errors.New("ERROR: attempt to create temporary directory in " + parent + " failed: " + err.Error())
+	}
+	defer os.RemoveAll(tmp)
+
+	err = extract(tarball, tmp)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(filepath.Join(tmp, markerFile), []byte(markerContents), 0644)
+	if err != nil {
+		return errors.New("ERROR: attempt to write " + markerFile + " in " + tmp + " failed: " + err.Error())
+	}
+	// MkdirTemp creates the directory with mode 0700.
+	err = os.Chmod(tmp, 0755)
+	if err != nil {
+		return errors.New("ERROR: attempt to set permissions of " + tmp + " failed: " + err.Error())
+	}
+
+	err = os.RemoveAll(dest)
+	if err != nil {
+		return errors.New("ERROR: attempt to remove " + dest + " failed: " + err.Error())
+	}
+	err = os.Rename(tmp, dest)
+	if err != nil {
+		return errors.New("ERROR: attempt to move extracted assets to " + dest + " failed: " + err.Error())
+	}
+	return nil
+}
+
+// checkReplaceable returns an error unless the given directory does not exist, is empty, or was created by
+// ExtractBases, so that nothing else, such as a working tree, is ever replaced.
+func checkReplaceable(dir string) error {
+	fi, err := os.Lstat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.New("ERROR: attempt to get information about " + dir + " failed: " + err.Error())
+	}
+	if !fi.IsDir() {
+		return errors.New("ERROR: " + dir + " is not a directory, so it cannot be replaced with the contents of " +
+			basesDir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.New("ERROR: attempt to read " + dir + " failed: " + err.Error())
+	}
+	marked := false
+	for _, e := range entries {
+		if strings.EqualFold(e.Name(), ".git") {
+			return errors.New("ERROR: " + dir + " holds a git repository, so it cannot be replaced with the contents of " +
+				basesDir)
+		}
+		marked = marked || e.Name() == markerFile
+	}
+	if len(entries) > 0 && !marked {
+		return errors.New("ERROR: " + dir + " was not created by gitops sync, so it is not replaced with the contents of " +
+			basesDir + " - remove it, or choose another path")
+	}
+	return nil
+}
+
+// extract writes the contents of the sas-bases directory in the given tarball to the dir directory.
+func extract(tarball, dir string) error {
+	f, err := os.Open(tarball)
+	if err != nil {
+		return errors.New("ERROR: attempt to open " + tarball + " failed: " + err.Error())
+	}
+	defer f.Close()
+
+	gzf, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.New("ERROR: gzip.NewReader() returned: " + err.Error())
+	}
+	tr := tar.NewReader(gzf)
+
+	found := false
+	links := map[string]bool{} // the links extracted so far
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.New("ERROR: attempt to read " + tarball + " failed: " + err.Error())
+		}
+
+		name := path.Clean(strings.TrimPrefix(h.Name, "./"))
+		rel, ok := strings.CutPrefix(name, basesDir+"/")
+		if !ok {
+			if name == basesDir {
+				found = true
+			}
+			continue
+		}
+		found = true
+		if !filepath.IsLocal(filepath.FromSlash(rel)) {
+			return errors.New("ERROR: " + tarball + " contains an entry outside of " + basesDir + ": " + h.Name)
+		}
+		// Writing through a link that was extracted earlier could escape dir, whatever the link points to.
+		for p := path.Dir(rel); p != "."; p = path.Dir(p) {
+			if links[p] {
+				return errors.New("ERROR: " + tarball + " contains an entry inside a link: " + h.Name)
+			}
+		}
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+
+		switch h.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = writeFile(target, tr, h.FileInfo().Mode())
+		case tar.TypeSymlink:
+			// Only allow links that stay inside sas-bases.
+			if filepath.IsAbs(h.Linkname) ||
+				!filepath.IsLocal(filepath.Join(filepath.Dir(filepath.FromSlash(rel)), filepath.FromSlash(h.Linkname))) {
+				return errors.New("ERROR: " + tarball + " contains a link that points outside of " + basesDir + ": " +
+					h.Name + " -> " + h.Linkname)
+			}
+			err = os.MkdirAll(filepath.Dir(target), 0755)
+			if err == nil {
+				err = os.Symlink(h.Linkname, target)
+			}
+			links[rel] = true
+		default:
+			// Other entry types have no place in a git repository.
+			continue
+		}
+		if err != nil {
+			return errors.New("ERROR: attempt to extract " + h.Name + " failed: " + err.Error())
+		}
+	}
+
+	if !found {
+		return errors.New("ERROR: " + tarball + " does not contain a " + basesDir + " directory")
+	}
+	return nil
+}
+
+// writeFile writes the contents of r to the given file, keeping only whether it is executable from the given mode.
+func writeFile(target string, r io.Reader, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+	perm := os.FileMode(0644)
+	if mode&0111 != 0 {
+		perm = 0755
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
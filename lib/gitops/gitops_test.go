@@ -0,0 +1,116 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gitops
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTarball writes deployment assets holding the given files, by path under sas-bases, to a file in the given
+// directory and returns its name.
+func writeTarball(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	name := filepath.Join(dir, "assets.tgz")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	for path, contents := range files {
+		err = tw.WriteHeader(&tar.Header{Name: basesDir + "/" + path, Mode: 0644, Size: int64(len(contents)),
+			Typeflag: tar.TypeReg})
+		if err == nil {
+			_, err = tw.Write([]byte(contents))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tw.Close(); err == nil {
+		err = gzw.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestCheckPath(t *testing.T) {
+	for path, valid := range map[string]bool{
+		"sas-bases":          true,
+		"base/sas-bases":     true,
+		"./base/../bases":    true,
+		"":                   false,
+		".":                  false,
+		"base/..":            false,
+		"..":                 false,
+		"../sas-bases":       false,
+		"/tmp/sas-bases":     false,
+		".git":               false,
+		".git/sas-bases":     false,
+		".GIT/hooks":         false,
+		"vendor/.git/config": false,
+	} {
+		err := CheckPath(path)
+		if valid && err != nil {
+			t.Errorf("CheckPath(%q) returned %v, want nil", path, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("CheckPath(%q) returned nil, want an error", path)
+		}
+	}
+}
+
+func TestExtractBasesReplacesOnlyItsOwnDirectories(t *testing.T) {
+	tmp := t.TempDir()
+	tarball := writeTarball(t, tmp, map[string]string{"checksums.txt": "Cadence Release: 1\n"})
+
+	// A working tree, and a directory of someone else's files, are never replaced.
+	worktree := filepath.Join(tmp, "worktree")
+	if err := os.MkdirAll(filepath.Join(worktree, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractBases(tarball, worktree); err == nil {
+		t.Error("ExtractBases replaced a working tree")
+	}
+	if _, err := os.Stat(filepath.Join(worktree, ".git")); err != nil {
+		t.Errorf(".git is gone after ExtractBases: %v", err)
+	}
+	other := filepath.Join(tmp, "other")
+	if err := os.MkdirAll(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(other, "keep.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractBases(tarball, other); err == nil {
+		t.Error("ExtractBases replaced a directory that it did not create")
+	}
+	if _, err := os.Stat(filepath.Join(other, "keep.txt")); err != nil {
+		t.Errorf("keep.txt is gone after ExtractBases: %v", err)
+	}
+
+	// A directory that ExtractBases created is replaced.
+	dest := filepath.Join(worktree, "base", "sas-bases")
+	if err := ExtractBases(tarball, dest); err != nil {
+		t.Fatalf("ExtractBases into a new directory returned %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, markerFile)); err != nil {
+		t.Errorf("the marker file was not written: %v", err)
+	}
+	tarball = writeTarball(t, tmp, map[string]string{"checksums.txt": "Cadence Release: 2\n"})
+	if err := ExtractBases(tarball, dest); err != nil {
+		t.Fatalf("ExtractBases into a directory that it created returned %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dest, "checksums.txt"))
+	if err != nil || string(b) != "Cadence Release: 2\n" {
+		t.Errorf("checksums.txt holds %q (%v) after the second ExtractBases", b, err)
+	}
+}
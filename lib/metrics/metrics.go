@@ -0,0 +1,193 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics keeps Prometheus metrics about order asset requests, for the commands that run for a long time to
+// expose.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace string = "viya4_orders"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	downloads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downloads_total",
+		Help:      "Order asset requests, by asset and status (success or failure).",
+	}, []string{"asset", "status"})
+	downloadBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_bytes_total",
+		Help:      "Bytes of order assets transferred, by asset and source (api or cache).",
+	}, []string{"asset", "source"})
+	downloadDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "download_duration_seconds",
+		Help:      "Time taken by order asset requests from start to finish, by asset.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"asset"})
+	apiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Time taken by the SAS Viya Orders API to respond to asset requests, by asset.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"asset"})
+	apiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_errors_total",
+		Help:      "Failed SAS Viya Orders API asset requests, by HTTP status (none if no response was received).",
+	}, []string{"status"})
+	tokenRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_requests_total",
+		Help:      "Bearer token requests, including refreshes of expired tokens, by result (success or failure).",
+	}, []string{"result"})
+	expiryDays = &expiryCollector{
+		desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "asset_expiry_days"),
+			"Days until the last license or certificates downloaded for an order expire.",
+			[]string{"order", "asset"}, nil),
+		expiries: map[[2]string]time.Time{},
+	}
+	latestRelease = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "latest_release_info",
+		Help: "The latest cadence release seen for an order, cadence name, and cadence version (empty for the " +
+			"latest version), in the release label. Always 1.",
+	}, []string{"order", "cadence_name", "cadence_version", "cadence", "release"})
+)
+
+func init() {
+	registry.MustRegister(downloads, downloadBytes, downloadDuration, apiDuration, apiErrors, tokenRequests,
+		expiryDays, latestRelease, collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+// Handler returns an HTTP handler that serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ListenAndServe serves the metrics at /metrics on the given address until the given context is done.
+func ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 30 * time.Second}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		return errors.New("ERROR: metrics server failed: " + err.Error())
+	case <-ctx.Done():
+	}
+	err := srv.Shutdown(context.Background())
+	if err != nil {
+		return errors.New("ERROR: metrics server shutdown failed: " + err.Error())
+	}
+	return nil
+}
+
+// ObserveDownload records the outcome and duration of a request for the given asset.
+func ObserveDownload(asset string, err error, d time.Duration) {
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+	downloads.WithLabelValues(asset, status).Inc()
+	downloadDuration.WithLabelValues(asset).Observe(d.Seconds())
+}
+
+// CountBytes returns a reader that adds the bytes read from the given reader to the bytes transferred for the given
+// asset from the given source, as they are read.
+func CountBytes(r io.Reader, asset, source string) io.Reader {
+	return &countingReader{r: r, c: downloadBytes.WithLabelValues(asset, source)}
+}
+
+// ObserveAPIRequest records how long the SAS Viya Orders API took to respond to a request for the given asset, and
+// counts the request as an error unless it succeeded or found the cached asset to be current. A status of 0 means that
+// no response was received.
+func ObserveAPIRequest(asset string, status int, d time.Duration) {
+	apiDuration.WithLabelValues(asset).Observe(d.Seconds())
+	switch status {
+	case http.StatusOK, http.StatusNotModified:
+	case 0:
+		apiErrors.WithLabelValues("none").Inc()
+	default:
+		apiErrors.WithLabelValues(strconv.Itoa(status)).Inc()
+	}
+}
+
+// TokenRequested records a Bearer token request, which failed if the given error is not nil.
+func TokenRequested(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	tokenRequests.WithLabelValues(result).Inc()
+}
+
+// SetExpiry records when the given asset of the given order expires.
+func SetExpiry(orderNum, asset string, exp time.Time) {
+	expiryDays.mu.Lock()
+	defer expiryDays.mu.Unlock()
+	expiryDays.expiries[[2]string{orderNum, asset}] = exp
+}
+
+// SetLatestRelease records the latest cadence release seen for the given order, cadence name, and cadence version,
+// replacing the one that was seen before.
+func SetLatestRelease(orderNum, cadenceName, cadenceVer, cadence, release string) {
+	latestRelease.DeletePartialMatch(prometheus.Labels{
+		"order":           orderNum,
+		"cadence_name":    cadenceName,
+		"cadence_version": cadenceVer,
+	})
+	latestRelease.WithLabelValues(orderNum, cadenceName, cadenceVer, cadence, release).Set(1)
+}
+
+// countingReader adds the bytes that are read through it to a counter.
+type countingReader struct {
+	r io.Reader
+	c prometheus.Counter
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.c.Add(float64(n))
+	return n, err
+}
+
+// expiryCollector reports the days until each recorded expiry, counted when the metrics are gathered so that they do
+// not go stale between downloads.
+type expiryCollector struct {
+	desc     *prometheus.Desc
+	mu       sync.Mutex
+	expiries map[[2]string]time.Time // by order number and asset name
+}
+
+func (ec *expiryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ec.desc
+}
+
+func (ec *expiryCollector) Collect(ch chan<- prometheus.Metric) {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	for k, exp := range ec.expiries {
+		ch <- prometheus.MustNewConstMetric(ec.desc, prometheus.GaugeValue, time.Until(exp).Hours()/24, k[0], k[1])
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Expiry returns when the asset with the given name, saved in the given file, expires. For a license, this is the exp
+// claim of the license JWT. For certificates, it is the earliest expiry of the certificates in the zip file. It
+// returns the zero time if the asset does not say when it expires.
+func Expiry(assetName, file string) (time.Time, error) {
+	if assetName != "license" && assetName != "certificates" {
+		return time.Time{}, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return time.Time{}, errors.New("ERROR: attempt to read " + file + " failed: " + err.Error())
+	}
+	return ExpiryOf(assetName, b, file)
+}
+
+// ExpiryOf returns when the asset with the given name and contents expires, as Expiry does for a file. The name
+// identifies the asset in error messages.
+func ExpiryOf(assetName string, b []byte, name string) (time.Time, error) {
+	switch assetName {
+	case "license":
+		return licenseExpiry(b, name)
+	case "certificates":
+		return certsExpiry(b, name)
+	}
+	return time.Time{}, nil
+}
+
+// licenseExpiry returns the expiry of the given license JWT.
+func licenseExpiry(b []byte, file string) (time.Time, error) {
+	parts := strings.Split(strings.TrimSpace(string(b)), ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("ERROR: " + file + " is not a JWT, so its expiry is not known")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, errors.New("ERROR: attempt to decode the claims in " + file + " failed: " + err.Error())
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return time.Time{}, errors.New("ERROR: attempt to parse the claims in " + file + " failed: " + err.Error())
+	}
+	if claims.Exp == "" {
+		return time.Time{}, nil
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, errors.New("ERROR: invalid exp claim in " + file + ": " + err.Error())
+	}
+	return time.Unix(int64(exp), 0).UTC(), nil
+}
+
+// certsExpiry returns the earliest expiry of the PEM certificates in the given zip file.
+func certsExpiry(b []byte, file string) (time.Time, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return time.Time{}, errors.New("ERROR: attempt to open " + file + " failed: " + err.Error())
+	}
+
+	var earliest time.Time
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return time.Time{}, errors.New("ERROR: attempt to read " + f.Name + " in " + file + " failed: " + err.Error())
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return time.Time{}, errors.New("ERROR: attempt to read " + f.Name + " in " + file + " failed: " + err.Error())
+		}
+
+		// Files that hold keys or anything else than certificates are skipped.
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return time.Time{}, errors.New("ERROR: attempt to parse a certificate in " + f.Name + " in " + file +
+					" failed: " + err.Error())
+			}
+			if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+				earliest = cert.NotAfter
+			}
+		}
+	}
+	return earliest, nil
+}
@@ -0,0 +1,282 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notify sends notifications about asset requests to webhooks, including Slack and Microsoft Teams incoming
+// webhooks.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+)
+
+// The events that notifications are sent for.
+const (
+	EventSuccess    string = "success"    // an asset was downloaded
+	EventFailure    string = "failure"    // an asset request failed
+	EventNewRelease string = "newRelease" // a new cadence release appeared
+	EventExpiry     string = "expiry"     // a downloaded license or certificate expires soon
+)
+
+// events lists every event, in the order that they are documented.
+var events = []string{EventSuccess, EventFailure, EventNewRelease, EventExpiry}
+
+// The types of targets, which differ in the payload that they are sent.
+const (
+	TypeWebhook string = "webhook" // the Event as JSON
+	TypeSlack   string = "slack"   // a Slack-compatible message
+	TypeTeams   string = "teams"   // a Microsoft Teams-compatible message card
+)
+
+// Target is a webhook that notifications are sent to, as configured in the notifications list of the config file.
+type Target struct {
+	Type   string   `mapstructure:"type"`   // webhook (the default), slack, or teams
+	URL    string   `mapstructure:"url"`    // the URL to POST the notifications to
+	Events []string `mapstructure:"events"` // the events to send notifications for (default is all of them)
+}
+
+// Event is a notification. Its JSON form is what is posted to a generic webhook: the information about the asset, as
+// printed by the CLI, plus what happened.
+type Event struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	assetreqs.Output
+	Error           string     `json:"error,omitempty"`
+	PreviousRelease string     `json:"previousRelease,omitempty"`
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Notifier sends notifications to targets.
+type Notifier struct {
+	targets      []Target
+	expiryWindow time.Duration
+	client       *http.Client
+}
+
+// New creates a Notifier for the given targets, which warns about licenses and certificates that expire within the
+// given number of days. Notifications are sent through the same proxy, and trust the same CA certificates, as requests
+// to the SAS Viya Orders API, as given by the apiclient.Config.
+func New(targets []Target, expiryDays int, cfg apiclient.Config) (*Notifier, error) {
+	for i, t := range targets {
+		if t.Type == "" {
+			targets[i].Type = TypeWebhook
+		} else if t.Type != TypeWebhook && t.Type != TypeSlack && t.Type != TypeTeams {
+			return nil, errors.New("ERROR: invalid notification type " + t.Type + " - expected webhook, slack, or teams")
+		}
+		u, err := url.Parse(t.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.New("ERROR: invalid notification URL " + t.URL + " - expected an http or https URL")
+		}
+		for _, e := range t.Events {
+			if !slices.Contains(events, e) {
+				return nil, errors.New("ERROR: invalid notification event " + e + " - expected one of " +
+					strings.Join(events, ", "))
+			}
+		}
+	}
+	if expiryDays < 0 {
+		return nil, errors.New("ERROR: invalid number of days before expiry to notify: " + fmt.Sprint(expiryDays))
+	}
+
+	t, err := apiclient.NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Notifier{
+		targets:      targets,
+		expiryWindow: time.Duration(expiryDays) * 24 * time.Hour,
+		client:       &http.Client{Transport: t, Timeout: 30 * time.Second},
+	}, nil
+}
+
+// AssetFetched sends a success or failure notification for an asset request. For a license or certificates that were
+// saved to a file, it also sends an expiry notification if they expire soon. Those that were written elsewhere, such
+// as to STDOUT or an HTTP response, are not read back: whoever has a copy of them calls Expires instead.
+func (n *Notifier) AssetFetched(output assetreqs.Output, err error) {
+	if err != nil {
+		n.Send(Event{Event: EventFailure, Output: output, Error: err.Error()})
+		return
+	}
+	n.Send(Event{Event: EventSuccess, Output: output})
+
+	if (output.AssetName != "license" && output.AssetName != "certificates") || output.AssetLocation == "-" {
+		return
+	}
+	exp, err := Expiry(output.AssetName, output.AssetLocation)
+	if err != nil {
+		slog.Warn("could not determine when the asset expires", "order", output.OrderNumber, "asset", output.AssetName,
+			"error", err)
+		return
+	}
+	n.Expires(output, exp)
+}
+
+// Expires sends an expiry notification for the asset described by the given output if it expires at the given time,
+// within the expiry window. The zero time means that the asset does not say when it expires.
+func (n *Notifier) Expires(output assetreqs.Output, exp time.Time) {
+	if !exp.IsZero() && time.Until(exp) <= n.expiryWindow {
+		n.Send(Event{Event: EventExpiry, Output: output, ExpiresAt: &exp})
+	}
+}
+
+// NewRelease sends a notification that a new cadence release, described by the given output, has appeared.
+func (n *Notifier) NewRelease(output assetreqs.Output, previous string) {
+	n.Send(Event{Event: EventNewRelease, Output: output, PreviousRelease: previous})
+}
+
+// Send sends the given event to each target that wants it. A notification that cannot be sent is logged rather than
+// stopping the CLI, since the request that it is about has already succeeded or failed.
+func (n *Notifier) Send(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	for _, t := range n.targets {
+		if len(t.Events) > 0 && !slices.Contains(t.Events, e.Event) {
+			continue
+		}
+		err := n.post(t, e)
+		if err != nil {
+			slog.Error("notification failed", "type", t.Type, "event", e.Event, "order", e.OrderNumber, "error", err)
+		}
+	}
+}
+
+// post posts the given event to the given target, in the form that the target expects.
+func (n *Notifier) post(t Target, e Event) error {
+	var payload any
+	switch t.Type {
+	case TypeSlack:
+		payload = slackPayload(e)
+	case TypeTeams:
+		payload = teamsPayload(e)
+	default:
+		payload = e
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return errors.New("ERROR: json.Marshal() returned: " + err.Error())
+	}
+
+	resp, err := n.client.Post(t.URL, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return errors.New("ERROR: " + e.Event + " notification to " + t.URL + " failed to complete: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("ERROR: " + e.Event + " notification to " + t.URL + " failed: " + resp.Status)
+	}
+	return nil
+}
+
+// title returns a one-line summary of the given event.
+func title(e Event) string {
+	asset := e.AssetName + " for order " + e.OrderNumber
+	switch e.Event {
+	case EventFailure:
+		return "Download of " + asset + " failed"
+	case EventNewRelease:
+		return "New release of " + e.Cadence + " for order " + e.OrderNumber + ": " + e.CadenceRelease
+	case EventExpiry:
+		return "The " + asset + " expires on " + e.ExpiresAt.Format("2006-01-02")
+	default:
+		return "Downloaded " + asset
+	}
+}
+
+// color returns the color that chat messages about the given event are marked with.
+func color(e Event) string {
+	switch e.Event {
+	case EventFailure:
+		return "#d72b3f"
+	case EventExpiry:
+		return "#e8a33d"
+	default:
+		return "#2eb67d"
+	}
+}
+
+// facts returns the names and values of the fields of the given event that are set, in the order that the CLI prints
+// them, for the chat messages. Fields with their zero value, such as the size of an asset that was not downloaded, are
+// left out.
+func facts(e Event) (names, values []string) {
+	add := func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.Anonymous || f.Name == "Event" || f.Name == "Time" {
+				continue
+			}
+			val := v.Field(i)
+			if val.IsZero() {
+				continue
+			}
+			if val.Kind() == reflect.Pointer {
+				val = val.Elem()
+			}
+			s := fmt.Sprint(val.Interface())
+			if t, ok := val.Interface().(time.Time); ok {
+				s = t.Format(time.RFC3339)
+			}
+			names = append(names, f.Name)
+			values = append(values, s)
+		}
+	}
+	add(reflect.ValueOf(e.Output))
+	add(reflect.ValueOf(e))
+	return names, values
+}
+
+// slackPayload returns a Slack-compatible incoming webhook message for the given event.
+func slackPayload(e Event) any {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	names, values := facts(e)
+	fields := []field{}
+	for i := range names {
+		fields = append(fields, field{Title: names[i], Value: values[i], Short: len(values[i]) < 40})
+	}
+	return map[string]any{
+		"text": title(e),
+		"attachments": []map[string]any{{
+			"color":  color(e),
+			"fields": fields,
+			"ts":     e.Time.Unix(),
+		}},
+	}
+}
+
+// teamsPayload returns a Microsoft Teams-compatible incoming webhook message card for the given event.
+func teamsPayload(e Event) any {
+	type fact struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	names, values := facts(e)
+	fs := []fact{}
+	for i := range names {
+		fs = append(fs, fact{Name: names[i], Value: values[i]})
+	}
+	return map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    title(e),
+		"title":      title(e),
+		"themeColor": strings.TrimPrefix(color(e), "#"),
+		"sections":   []map[string]any{{"facts": fs}},
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+)
+
+// target is a webhook that records the payloads that it is sent.
+type target struct {
+	mu       sync.Mutex
+	payloads []map[string]any
+	hosts    []string // the hosts that the requests were for, which differ from that of the target when proxied
+}
+
+func (tg *target) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var p map[string]any
+	b, _ := io.ReadAll(r.Body)
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || json.Unmarshal(b, &p) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	tg.payloads = append(tg.payloads, p)
+	tg.hosts = append(tg.hosts, r.URL.Host)
+}
+
+// sent returns the payloads that the target has been sent.
+func (tg *target) sent() []map[string]any {
+	tg.mu.Lock()
+	defer tg.mu.Unlock()
+	return tg.payloads
+}
+
+// startTarget starts a webhook that records the payloads that it is sent, and returns it and its URL.
+func startTarget(t *testing.T) (*target, string) {
+	t.Helper()
+	tg := &target{}
+	srv := httptest.NewServer(tg)
+	t.Cleanup(srv.Close)
+	return tg, srv.URL
+}
+
+var output = assetreqs.Output{OrderNumber: "923457", AssetName: "deploymentAssets",
+	AssetReqURL: "https://api.apiproxy.sas.com/mysas/orders/923457/cadenceNames/stable/deploymentAssets",
+	Cadence:     "Stable 2026.01", CadenceRelease: "20260215.1771111111111"}
+
+var sentAt = time.Date(2026, 2, 16, 9, 36, 7, 0, time.UTC)
+
+func TestSendWebhook(t *testing.T) {
+	tg, url := startTarget(t)
+	n, err := New([]Target{{URL: url}}, 30, apiclient.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Send(Event{Event: EventNewRelease, Time: sentAt, Output: output, PreviousRelease: "20260127.1769510312235"})
+
+	// A generic webhook is sent the event as JSON.
+	sent := tg.sent()
+	if len(sent) != 1 {
+		t.Fatalf("the webhook was sent %d notifications, want 1", len(sent))
+	}
+	for k, v := range map[string]any{"event": "newRelease", "time": "2026-02-16T09:36:07Z", "orderNumber": "923457",
+		"assetName": "deploymentAssets", "cadence": "Stable 2026.01", "cadenceRelease": "20260215.1771111111111",
+		"previousRelease": "20260127.1769510312235"} {
+		if sent[0][k] != v {
+			t.Errorf("the webhook was sent %s: %v, want %v", k, sent[0][k], v)
+		}
+	}
+	for _, k := range []string{"error", "expiresAt"} {
+		if _, ok := sent[0][k]; ok {
+			t.Errorf("the webhook was sent %s, which the event does not have", k)
+		}
+	}
+}
+
+func TestSendSlack(t *testing.T) {
+	tg, url := startTarget(t)
+	n, err := New([]Target{{Type: TypeSlack, URL: url}}, 30, apiclient.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Send(Event{Event: EventFailure, Time: sentAt, Output: output, Error: "ERROR: the credentials were not accepted"})
+
+	sent := tg.sent()
+	if len(sent) != 1 {
+		t.Fatalf("the Slack webhook was sent %d notifications, want 1", len(sent))
+	}
+	var msg struct {
+		Text        string `json:"text"`
+		Attachments []struct {
+			Color  string `json:"color"`
+			Fields []struct {
+				Title string `json:"title"`
+				Value string `json:"value"`
+				Short bool   `json:"short"`
+			} `json:"fields"`
+			TS int64 `json:"ts"`
+		} `json:"attachments"`
+	}
+	remarshal(t, sent[0], &msg)
+	if msg.Text != "Download of deploymentAssets for order 923457 failed" || len(msg.Attachments) != 1 {
+		t.Fatalf("the Slack webhook was sent %+v", msg)
+	}
+	a := msg.Attachments[0]
+	if a.Color != "#d72b3f" || a.TS != sentAt.Unix() {
+		t.Errorf("the Slack message has the color %s and time %d", a.Color, a.TS)
+	}
+	// The fields that are set are listed in the order that the CLI prints them, followed by the error.
+	want := []string{"OrderNumber", "AssetName", "AssetReqURL", "Cadence", "CadenceRelease", "Error"}
+	if len(a.Fields) != len(want) {
+		t.Fatalf("the Slack message has the fields %+v, want %v", a.Fields, want)
+	}
+	for i, f := range a.Fields {
+		if f.Title != want[i] {
+			t.Errorf("field %d of the Slack message is %s, want %s", i, f.Title, want[i])
+		}
+		if f.Short != (len(f.Value) < 40) {
+			t.Errorf("the Slack message field %s: %q is short %t", f.Title, f.Value, f.Short)
+		}
+	}
+	if f := a.Fields[len(a.Fields)-1]; f.Value != "ERROR: the credentials were not accepted" {
+		t.Errorf("the Slack message has the error %q", f.Value)
+	}
+}
+
+func TestSendTeams(t *testing.T) {
+	tg, url := startTarget(t)
+	n, err := New([]Target{{Type: TypeTeams, URL: url}}, 30, apiclient.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	license := output
+	license.AssetName = "license"
+	n.Send(Event{Event: EventExpiry, Time: sentAt, Output: license, ExpiresAt: &exp})
+
+	sent := tg.sent()
+	if len(sent) != 1 {
+		t.Fatalf("the Teams webhook was sent %d notifications, want 1", len(sent))
+	}
+	var card struct {
+		Type       string `json:"@type"`
+		Context    string `json:"@context"`
+		Summary    string `json:"summary"`
+		Title      string `json:"title"`
+		ThemeColor string `json:"themeColor"`
+		Sections   []struct {
+			Facts []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"facts"`
+		} `json:"sections"`
+	}
+	remarshal(t, sent[0], &card)
+	const title = "The license for order 923457 expires on 2026-03-01"
+	if card.Type != "MessageCard" || card.Context != "https://schema.org/extensions" || card.Title != title ||
+		card.Summary != title || card.ThemeColor != "e8a33d" || len(card.Sections) != 1 {
+		t.Fatalf("the Teams webhook was sent %+v", card)
+	}
+	facts := card.Sections[0].Facts
+	if last := facts[len(facts)-1]; last.Name != "ExpiresAt" || last.Value != "2026-03-01T00:00:00Z" {
+		t.Errorf("the Teams message card ends with the fact %s: %s", last.Name, last.Value)
+	}
+}
+
+// remarshal converts the given payload, as decoded from JSON, to the given type.
+func remarshal(t *testing.T, payload map[string]any, v any) {
+	t.Helper()
+	b, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(b, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventsOf returns the events of the given payloads.
+func eventsOf(payloads []map[string]any) []any {
+	var es []any
+	for _, p := range payloads {
+		es = append(es, p["event"])
+	}
+	return es
+}
+
+func TestSendEvents(t *testing.T) {
+	all, allURL := startTarget(t)
+	failures, failuresURL := startTarget(t)
+	n, err := New([]Target{{URL: allURL}, {URL: failuresURL, Events: []string{EventFailure}}}, 30, apiclient.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.AssetFetched(output, nil)
+	n.AssetFetched(output, errors.New("ERROR: the order was not found"))
+
+	if es := eventsOf(all.sent()); len(es) != 2 || es[0] != EventSuccess || es[1] != EventFailure {
+		t.Errorf("the target for all events was sent %v", es)
+	}
+	if es := eventsOf(failures.sent()); len(es) != 1 || es[0] != EventFailure {
+		t.Errorf("the target for failures was sent %v", es)
+	}
+}
+
+// writeLicense writes a license JWT that expires at the given time to a file, and returns its name.
+func writeLicense(t *testing.T, exp time.Time) string {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	jwt := enc.EncodeToString([]byte(`{"alg":"ES256"}`)) + "." +
+		enc.EncodeToString([]byte(`{"exp":`+strconv.FormatInt(exp.Unix(), 10)+`}`)) + ".c2ln"
+	file := filepath.Join(t.TempDir(), "SASViyaV4_923457_license.jwt")
+	if err := os.WriteFile(file, []byte(jwt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestAssetFetchedExpiry(t *testing.T) {
+	exp := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second).UTC()
+	license := output
+	license.AssetName = "license"
+	license.AssetLocation = writeLicense(t, exp)
+
+	for _, tc := range []struct {
+		days     int
+		location string
+		expiry   bool
+	}{
+		{days: 30, location: license.AssetLocation, expiry: true},
+		{days: 5, location: license.AssetLocation},
+		// A license that was not saved to a file is not read back.
+		{days: 30, location: "-"},
+	} {
+		tg, url := startTarget(t)
+		n, err := New([]Target{{URL: url}}, tc.days, apiclient.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		o := license
+		o.AssetLocation = tc.location
+		n.AssetFetched(o, nil)
+
+		want := 1
+		if tc.expiry {
+			want++
+		}
+		sent := tg.sent()
+		if es := eventsOf(sent); len(es) != want || es[0] != EventSuccess {
+			t.Errorf("license at %s expiring in 10 days, with %d days' warning, sent %v", tc.location, tc.days, es)
+			continue
+		}
+		if tc.expiry && (sent[1]["event"] != EventExpiry || sent[1]["expiresAt"] != exp.Format(time.RFC3339)) {
+			t.Errorf("license expiring at %s sent the expiry notification %v", exp, sent[1])
+		}
+	}
+}
+
+func TestExpires(t *testing.T) {
+	tg, url := startTarget(t)
+	n, err := New([]Target{{URL: url}}, 30, apiclient.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs := output
+	certs.AssetName, certs.AssetLocation = "certificates", "-"
+
+	n.Expires(certs, time.Time{})
+	n.Expires(certs, time.Now().Add(60*24*time.Hour))
+	if sent := tg.sent(); len(sent) != 0 {
+		t.Errorf("certificates that do not expire soon sent %v", eventsOf(sent))
+	}
+	n.Expires(certs, time.Now().Add(24*time.Hour))
+	if es := eventsOf(tg.sent()); len(es) != 1 || es[0] != EventExpiry {
+		t.Errorf("certificates that expire tomorrow sent %v", es)
+	}
+}
+
+func TestSendThroughProxy(t *testing.T) {
+	proxy, proxyURL := startTarget(t)
+	n, err := New([]Target{{URL: "http://hooks.example.test/viya"}}, 30, apiclient.Config{Proxy: proxyURL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.Send(Event{Event: EventSuccess, Output: output})
+
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if len(proxy.hosts) != 1 || proxy.hosts[0] != "hooks.example.test" {
+		t.Errorf("the proxy was sent notifications for %v, want [hooks.example.test]", proxy.hosts)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		targets []Target
+		days    int
+		cfg     apiclient.Config
+	}{
+		{name: "unknown type", targets: []Target{{Type: "email", URL: "https://hooks.example.com"}}},
+		{name: "URL without a host", targets: []Target{{URL: "https:///hooks"}}},
+		{name: "URL that is not HTTP", targets: []Target{{URL: "ftp://hooks.example.com"}}},
+		{name: "unknown event", targets: []Target{{URL: "https://hooks.example.com", Events: []string{"start"}}}},
+		{name: "negative days", targets: []Target{{URL: "https://hooks.example.com"}}, days: -1},
+		{name: "missing CA file", targets: []Target{{URL: "https://hooks.example.com"}},
+			cfg: apiclient.Config{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+	} {
+		if _, err := New(tc.targets, tc.days, tc.cfg); err == nil {
+			t.Errorf("%s: New returned no error", tc.name)
+		}
+	}
+
+	targets := []Target{{URL: "https://hooks.example.com"}}
+	if _, err := New(targets, 0, apiclient.Config{}); err != nil || targets[0].Type != TypeWebhook {
+		t.Errorf("New of a target without a type returned %v and the type %q", err, targets[0].Type)
+	}
+}
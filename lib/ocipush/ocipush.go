@@ -0,0 +1,179 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ocipush pushes order assets to a container registry as OCI artifacts.
+package ocipush
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/file"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+// ArtifactType is the artifact type of the OCI manifests that order assets are pushed with.
+const ArtifactType string = "application/vnd.sas.viya.orders.assets.v1"
+
+// Annotations that are set on the manifest of a pushed artifact.
+const (
+	AnnotationOrderNumber    string = "com.sas.viya.order.number"
+	AnnotationCadence        string = "com.sas.viya.cadence"
+	AnnotationCadenceRelease string = "com.sas.viya.cadence.release"
+)
+
+// mediaTypes maps the asset names to the media types of the layers that hold them.
+var mediaTypes = map[string]string{
+	"deploymentAssets": "application/vnd.sas.viya.orders.deployment-assets.v1.tar+gzip",
+	"license":          "application/vnd.sas.viya.orders.license.v1.jwt",
+	"certificates":     "application/vnd.sas.viya.orders.certificates.v1.zip",
+}
+
+// invalidTagChars matches the characters that are not allowed in a tag.
+var invalidTagChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// Config provides the settings used to connect to the registry.
+type Config struct {
+	Username  string // if not set, credentials are taken from the Docker config file
+	Password  string
+	PlainHTTP bool // use HTTP rather than HTTPS
+}
+
+// Asset is an order asset that has been saved to a file.
+type Asset struct {
+	Name string // the asset name, for example deploymentAssets
+	Path string
+}
+
+// Artifact describes an artifact to push.
+type Artifact struct {
+	Assets         []Asset
+	OrderNumber    string
+	Cadence        string
+	CadenceRelease string
+}
+
+// Pusher pushes artifacts to a repository in a registry.
+type Pusher struct {
+	repo *remote.Repository
+	tag  string
+}
+
+// New creates a Pusher for the given oci://registry/repository[:tag] destination.
+func New(dest string, cfg Config) (*Pusher, error) {
+	ref, ok := strings.CutPrefix(dest, "oci://")
+	if !ok {
+		return nil, errors.New("ERROR: invalid push destination " + dest + " - expected oci://registry/repository[:tag]")
+	}
+	repo, err := remote.NewRepository(ref)
+	if err != nil {
+		return nil, errors.New("ERROR: invalid push destination " + dest + ": " + err.Error())
+	}
+	tag := repo.Reference.Reference
+	if strings.Contains(tag, ":") {
+		return nil, errors.New("ERROR: invalid push destination " + dest + " - a tag, not a digest, is expected")
+	}
+	repo.PlainHTTP = cfg.PlainHTTP
+
+	var cred auth.CredentialFunc
+	if cfg.Username != "" {
+		cred = auth.StaticCredential(repo.Reference.Registry,
+			auth.Credential{Username: cfg.Username, Password: cfg.Password})
+	} else {
+		store, err := credentials.NewStoreFromDocker(credentials.StoreOptions{})
+		if err != nil {
+			return nil, errors.New("ERROR: attempt to read Docker credentials failed: " + err.Error())
+		}
+		cred = credentials.Credential(store)
+	}
+	repo.Client = &auth.Client{
+		Client:     retry.DefaultClient,
+		Cache:      auth.NewCache(),
+		Credential: cred,
+	}
+
+	return &Pusher{repo: repo, tag: tag}, nil
+}
+
+// DefaultTag returns the tag that an artifact is pushed with when the destination does not give one:
+// <cadence>-<release>, in lowercase and with spaces replaced by dashes.
+func DefaultTag(cadence, release string) string {
+	tag := invalidTagChars.ReplaceAllString(strings.ToLower(cadence+"-"+release), "-")
+	tag = strings.Trim(tag, "-.")
+	if len(tag) > 128 {
+		tag = tag[:128]
+	}
+	return tag
+}
+
+// Push packages the assets of the given artifact, with annotations for its order number, cadence and release, and
+// pushes it to the repository. It returns the reference that the artifact was pushed to and its manifest digest.
+func (p *Pusher) Push(a Artifact) (reference string, digest string, err error) {
+	tag := p.tag
+	if tag == "" {
+		tag = DefaultTag(a.Cadence, a.CadenceRelease)
+		if tag == "" {
+			return "", "", errors.New("ERROR: no tag given for the push destination and no cadence information to " +
+				"make one from")
+		}
+	}
+	reference = p.repo.Reference.Registry + "/" + p.repo.Reference.Repository + ":" + tag
+
+	ctx := context.Background()
+	// The file store reads the assets in place, so its working directory is only used to resolve relative paths.
+	fs, err := file.New("")
+	if err != nil {
+		return reference, "", errors.New("ERROR: setup of OCI file store failed: " + err.Error())
+	}
+	defer fs.Close()
+
+	var layers []ocispec.Descriptor
+	for _, asset := range a.Assets {
+		mt, ok := mediaTypes[asset.Name]
+		if !ok {
+			return reference, "", errors.New("ERROR: " + asset.Name + " cannot be pushed as part of an OCI artifact")
+		}
+		desc, err := fs.Add(ctx, filepath.Base(asset.Path), mt, asset.Path)
+		if err != nil {
+			return reference, "", errors.New("ERROR: attempt to add " + asset.Path + " to OCI artifact failed: " +
+				err.Error())
+		}
+		layers = append(layers, desc)
+	}
+
+	annotations := map[string]string{}
+	for k, v := range map[string]string{
+		AnnotationOrderNumber:    a.OrderNumber,
+		AnnotationCadence:        a.Cadence,
+		AnnotationCadenceRelease: a.CadenceRelease,
+	} {
+		if v != "" {
+			annotations[k] = v
+		}
+	}
+
+	manifest, err := oras.PackManifest(ctx, fs, oras.PackManifestVersion1_1, ArtifactType,
+		oras.PackManifestOptions{Layers: layers, ManifestAnnotations: annotations})
+	if err != nil {
+		return reference, "", errors.New("ERROR: attempt to pack OCI artifact failed: " + err.Error())
+	}
+	err = fs.Tag(ctx, manifest, tag)
+	if err != nil {
+		return reference, "", errors.New("ERROR: attempt to tag OCI artifact failed: " + err.Error())
+	}
+
+	_, err = oras.Copy(ctx, fs, tag, p.repo, tag, oras.DefaultCopyOptions)
+	if err != nil {
+		return reference, "", errors.New("ERROR: push to " + reference + " failed: " + err.Error())
+	}
+
+	return reference, manifest.Digest.String(), nil
+}
@@ -0,0 +1,272 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ocipush
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// stubRegistry is just enough of an OCI distribution registry to push artifacts to: blobs are uploaded in one piece,
+// and manifests are stored by tag and by digest.
+type stubRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte // by digest
+	manifests map[string][]byte // by tag and by digest, under the repository: repo/ref
+	uploads   int
+	deny      bool // if set, pushes are refused
+}
+
+func newStubRegistry() *stubRegistry {
+	return &stubRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+}
+
+func (s *stubRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.URL.Path == "/v2/" {
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	if s.deny && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = io.WriteString(w, `{"errors":[{"code":"DENIED","message":"requested access to the resource is denied"}]}`)
+		return
+	}
+
+	switch repo, ref, _ := cutLast(path, "/manifests/"); {
+	case ref != "":
+		switch r.Method {
+		case http.MethodHead, http.MethodGet:
+			b, ok := s.manifests[repo+"/"+ref]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+			w.Header().Set("Docker-Content-Digest", digest.FromBytes(b).String())
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(b)
+			}
+		case http.MethodPut:
+			b, _ := io.ReadAll(r.Body)
+			d := digest.FromBytes(b).String()
+			s.manifests[repo+"/"+ref] = b
+			s.manifests[repo+"/"+d] = b
+			w.Header().Set("Docker-Content-Digest", d)
+			w.WriteHeader(http.StatusCreated)
+		}
+		return
+	}
+
+	switch _, d, _ := cutLast(path, "/blobs/"); {
+	case strings.HasPrefix(d, "uploads/") && r.Method == http.MethodPost:
+		s.uploads++
+		w.Header().Set("Location", r.URL.Path+strconv.Itoa(s.uploads))
+		w.WriteHeader(http.StatusAccepted)
+	case strings.HasPrefix(d, "uploads/") && r.Method == http.MethodPut:
+		b, _ := io.ReadAll(r.Body)
+		if d := r.URL.Query().Get("digest"); d != digest.FromBytes(b).String() {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+		s.blobs[digest.FromBytes(b).String()] = b
+		w.WriteHeader(http.StatusCreated)
+	case d != "" && (r.Method == http.MethodHead || r.Method == http.MethodGet):
+		b, ok := s.blobs[d]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		w.Header().Set("Docker-Content-Digest", d)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(b)
+		}
+	default:
+		http.Error(w, "not supported", http.StatusNotImplemented)
+	}
+}
+
+// manifest returns the manifest in the given repository with the given tag, and its digest.
+func (s *stubRegistry) manifest(t *testing.T, repo, tag string) (ocispec.Manifest, string) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var m ocispec.Manifest
+	b, ok := s.manifests[repo+"/"+tag]
+	if !ok {
+		t.Fatalf("no manifest was pushed to %s:%s", repo, tag)
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m, digest.FromBytes(b).String()
+}
+
+// blob returns the blob with the given digest, if there is one.
+func (s *stubRegistry) blob(d string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.blobs[d]
+	return b, ok
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// startRegistry starts a stub registry, and returns it and its host.
+func startRegistry(t *testing.T) (*stubRegistry, string) {
+	t.Helper()
+	reg := newStubRegistry()
+	srv := httptest.NewServer(reg)
+	t.Cleanup(srv.Close)
+	return reg, strings.TrimPrefix(srv.URL, "http://")
+}
+
+// writeAssets writes a file for each of the given assets, with the asset name as its contents, and returns them.
+func writeAssets(t *testing.T, names ...string) []Asset {
+	t.Helper()
+	dir := t.TempDir()
+	var assets []Asset
+	for _, name := range names {
+		path := filepath.Join(dir, "SASViyaV4_923457_"+name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		assets = append(assets, Asset{Name: name, Path: path})
+	}
+	return assets
+}
+
+func TestDefaultTag(t *testing.T) {
+	for _, tc := range []struct {
+		cadence, release, tag string
+	}{
+		{"Stable 2026.01", "20260215.1771111111111", "stable-2026.01-20260215.1771111111111"},
+		{"LTS 2025.09", "", "lts-2025.09"},
+		{"", "", ""},
+		{"Stable  2026.01 (preview)", "x", "stable-2026.01-preview--x"},
+		{"Stable 2026.01", strings.Repeat("1", 200), ("stable-2026.01-" + strings.Repeat("1", 200))[:128]},
+	} {
+		if tag := DefaultTag(tc.cadence, tc.release); tag != tc.tag {
+			t.Errorf("DefaultTag(%q, %q) returned %q, want %q", tc.cadence, tc.release, tag, tc.tag)
+		}
+	}
+}
+
+func TestPush(t *testing.T) {
+	reg, host := startRegistry(t)
+	p, err := New("oci://"+host+"/sas/assets", Config{Username: "user", Password: "password", PlainHTTP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assets := writeAssets(t, "deploymentAssets", "license")
+	ref, dgst, err := p.Push(Artifact{Assets: assets, OrderNumber: "923457", Cadence: "Stable 2026.01",
+		CadenceRelease: "20260215.1771111111111"})
+	if err != nil {
+		t.Fatalf("Push returned %v", err)
+	}
+
+	// Without a tag in the destination, the artifact is tagged with its cadence and release.
+	const tag = "stable-2026.01-20260215.1771111111111"
+	if ref != host+"/sas/assets:"+tag {
+		t.Errorf("Push returned the reference %s", ref)
+	}
+	m, mDigest := reg.manifest(t, "sas/assets", tag)
+	if dgst != mDigest {
+		t.Errorf("Push returned the digest %s for a manifest with digest %s", dgst, mDigest)
+	}
+	if m.ArtifactType != ArtifactType || m.MediaType != ocispec.MediaTypeImageManifest {
+		t.Errorf("the artifact has the artifact type %s and media type %s", m.ArtifactType, m.MediaType)
+	}
+	want := map[string]string{
+		AnnotationOrderNumber:    "923457",
+		AnnotationCadence:        "Stable 2026.01",
+		AnnotationCadenceRelease: "20260215.1771111111111",
+	}
+	for k, v := range want {
+		if m.Annotations[k] != v {
+			t.Errorf("the manifest has the annotation %s: %q, want %q", k, m.Annotations[k], v)
+		}
+	}
+
+	// There is a layer for each asset, of its media type, named after its file.
+	if len(m.Layers) != len(assets) {
+		t.Fatalf("the manifest has %d layers, want %d", len(m.Layers), len(assets))
+	}
+	for i, l := range m.Layers {
+		if l.MediaType != mediaTypes[assets[i].Name] || l.Annotations[ocispec.AnnotationTitle] !=
+			filepath.Base(assets[i].Path) {
+			t.Errorf("layer %d has media type %s and title %s, want %s and %s", i, l.MediaType,
+				l.Annotations[ocispec.AnnotationTitle], mediaTypes[assets[i].Name], filepath.Base(assets[i].Path))
+		}
+		if b, ok := reg.blob(l.Digest.String()); !ok || string(b) != assets[i].Name {
+			t.Errorf("layer %d holds %q, want %q", i, b, assets[i].Name)
+		}
+	}
+}
+
+func TestPushWithTag(t *testing.T) {
+	reg, host := startRegistry(t)
+	p, err := New("oci://"+host+"/sas/assets:nightly", Config{Username: "user", PlainHTTP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without cadence information, only the order number is annotated.
+	ref, _, err := p.Push(Artifact{Assets: writeAssets(t, "certificates"), OrderNumber: "923457"})
+	if err != nil || ref != host+"/sas/assets:nightly" {
+		t.Fatalf("Push returned %s, %v", ref, err)
+	}
+	m, _ := reg.manifest(t, "sas/assets", "nightly")
+	if len(m.Annotations) != 2 || m.Annotations[AnnotationOrderNumber] != "923457" {
+		// The other annotation is the creation time that oras adds.
+		t.Errorf("the manifest has the annotations %v", m.Annotations)
+	}
+}
+
+func TestPushFailures(t *testing.T) {
+	reg, host := startRegistry(t)
+	p, err := New("oci://"+host+"/sas/assets", Config{Username: "user", PlainHTTP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = p.Push(Artifact{Assets: writeAssets(t, "certificates"), OrderNumber: "923457"}); err == nil {
+		t.Error("Push without a tag or cadence information returned no error")
+	}
+	if _, _, err = p.Push(Artifact{Assets: writeAssets(t, "assetHistory"), Cadence: "Stable 2026.01"}); err == nil {
+		t.Error("Push of an asset that has no media type returned no error")
+	}
+	reg.mu.Lock()
+	reg.deny = true
+	reg.mu.Unlock()
+	if _, _, err = p.Push(Artifact{Assets: writeAssets(t, "license"), Cadence: "Stable 2026.01"}); err == nil {
+		t.Error("Push that the registry refused returned no error")
+	}
+
+	for _, dest := range []string{host + "/sas/assets", "oci://" + host, "oci://" + host + "/sas/assets@sha256:" +
+		strings.Repeat("0", 64)} {
+		if _, err = New(dest, Config{Username: "user"}); err == nil {
+			t.Errorf("New accepted the destination %s", dest)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package orderstest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// modTime is the modification time of the files in fabricated assets, so that the same asset always has the same
+// contents.
+var modTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// deploymentAssets fabricates the deployment assets of the given order at the given cadence release: a tarball of a
+// small sas-bases directory, with a checksums.txt file that has the cadence information, as the real ones do.
+func deploymentAssets(orderNum string, c Cadence, release string) ([]byte, error) {
+	files := []struct{ name, data string }{
+		{"sas-bases/README.md", "# Deployment assets for SAS Viya order " + orderNum + "\n\nThese assets were " +
+			"fabricated by the mock SAS Viya Orders API and cannot be used to deploy SAS Viya.\n"},
+		{"sas-bases/base/kustomization.yaml", "resources:\n- sas-viya.yaml\n"},
+		{"sas-bases/base/sas-viya.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: sas-deployment-metadata\n" +
+			"data:\n  SAS_ORDER_NUMBER: \"" + orderNum + "\"\n  SAS_CADENCE_NAME: " + c.Name + "\n" +
+			"  SAS_CADENCE_VERSION: \"" + c.Version + "\"\n  SAS_CADENCE_RELEASE: \"" + release + "\"\n"},
+		{"sas-bases/examples/README.md", "# Examples\n"},
+	}
+
+	var checksums strings.Builder
+	checksums.WriteString("Cadence Display Name: " + c.display() + "\n")
+	checksums.WriteString("Cadence Release: " + release + "\n\n")
+	for _, f := range files {
+		sum := sha256.Sum256([]byte(f.data))
+		checksums.WriteString(hex.EncodeToString(sum[:]) + "  " + f.name + "\n")
+	}
+	files = append(files, struct{ name, data string }{"sas-bases/checksums.txt", checksums.String()})
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	dirs := map[string]bool{}
+	for _, f := range files {
+		// Add the directories of each file first, as tar does.
+		parts := strings.Split(f.name, "/")
+		for i := 1; i < len(parts); i++ {
+			dir := strings.Join(parts[:i], "/") + "/"
+			if dirs[dir] {
+				continue
+			}
+			dirs[dir] = true
+			err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir, Mode: 0755, ModTime: modTime})
+			if err != nil {
+				return nil, errors.New("ERROR: attempt to write deployment assets failed: " + err.Error())
+			}
+		}
+		err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: f.name, Mode: 0644, Size: int64(len(f.data)),
+			ModTime: modTime})
+		if err == nil {
+			_, err = tw.Write([]byte(f.data))
+		}
+		if err != nil {
+			return nil, errors.New("ERROR: attempt to write deployment assets failed: " + err.Error())
+		}
+	}
+	err := tw.Close()
+	if err == nil {
+		err = gw.Close()
+	}
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to write deployment assets failed: " + err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// license fabricates the license of the given order at the given cadence: a JWT, signed with the given key, that
+// expires at the given time.
+func license(orderNum string, c Cadence, key []byte, issued, expires time.Time) ([]byte, error) {
+	enc := base64.RawURLEncoding
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return nil, errors.New("ERROR: json.Marshal() returned: " + err.Error())
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":            "orderstest",
+		"sub":            orderNum,
+		"iat":            issued.Unix(),
+		"exp":            expires.Unix(),
+		"cadenceName":    c.Name,
+		"cadenceVersion": c.Version,
+	})
+	if err != nil {
+		return nil, errors.New("ERROR: json.Marshal() returned: " + err.Error())
+	}
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return []byte(unsigned + "." + enc.EncodeToString(mac.Sum(nil)) + "\n"), nil
+}
+
+// certificates fabricates the certificates of the given order: a zip file with a CA certificate, and an entitlement
+// certificate and key signed by it, which expire at the given time.
+func certificates(orderNum string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, issued, expires time.Time) ([]byte,
+	error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to generate a key failed: " + err.Error())
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serialNumber(orderNum),
+		Subject:      pkix.Name{CommonName: orderNum, Organization: []string{"orderstest"}},
+		NotBefore:    issued,
+		NotAfter:     expires,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to create a certificate failed: " + err.Error())
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to encode a key failed: " + err.Error())
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name  string
+		block *pem.Block
+	}{
+		{"SAS_CA_Certificate.pem", &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}},
+		{"entitlement_certificate.pem", &pem.Block{Type: "CERTIFICATE", Bytes: der}},
+		{"entitlement_key.pem", &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modTime})
+		if err == nil {
+			err = pem.Encode(w, f.block)
+		}
+		if err != nil {
+			return nil, errors.New("ERROR: attempt to write certificates failed: " + err.Error())
+		}
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to write certificates failed: " + err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// newCA creates the CA that signs the entitlement certificates, valid until the given time.
+func newCA(issued, expires time.Time) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.New("ERROR: attempt to generate a key failed: " + err.Error())
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "orderstest CA", Organization: []string{"orderstest"}},
+		NotBefore:             issued,
+		NotAfter:              expires,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, errors.New("ERROR: attempt to create the CA certificate failed: " + err.Error())
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, errors.New("ERROR: attempt to parse the CA certificate failed: " + err.Error())
+	}
+	return ca, key, nil
+}
+
+// serialNumber returns a certificate serial number derived from the given order number.
+func serialNumber(orderNum string) *big.Int {
+	sum := sha256.Sum256([]byte(orderNum))
+	return new(big.Int).SetBytes(sum[:8])
+}
+
+// fileName returns the name that the real API gives the given asset in its Content-Disposition header.
+func fileName(orderNum, assetName string, c Cadence, release string, t time.Time) string {
+	switch assetName {
+	case "certificates":
+		return "SASViyaV4_" + orderNum + "_certs.zip"
+	case "license":
+		return fmt.Sprintf("SASViyaV4_%s_0_%s_%s_license_%d.jwt", orderNum, c.Name, c.Version, t.UnixMilli())
+	default:
+		return fmt.Sprintf("SASViyaV4_%s_0_%s_%s_%s_deploymentAssets_%d.tgz", orderNum, c.Name, c.Version, release,
+			t.UnixMilli())
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package orderstest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fault makes the API misbehave for the requests that it matches.
+type Fault struct {
+	Path     string        // only requests whose path contains this (every request if empty)
+	Status   int           // respond with this HTTP status, such as 401, 404, 429, or 503, instead of as usual
+	SlowBody time.Duration // send the response body slowly, spread over this long
+	Truncate bool          // stop sending the response body halfway through, as if the connection dropped
+	Times    int           // only for this many matching requests (every one if 0)
+}
+
+// ParseFault parses a fault from a comma-separated list of settings, such as
+// "path=deploymentAssets,status=503,times=2", "slow=30s", or "path=certificates,truncate". The settings are path,
+// status, slow, truncate, and times, which set the fields of the Fault of the same meaning.
+func ParseFault(spec string) (Fault, error) {
+	var f Fault
+	for _, s := range strings.Split(spec, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(s), "=")
+		var err error
+		switch name {
+		case "path":
+			f.Path = value
+		case "status":
+			f.Status, err = strconv.Atoi(value)
+			if err == nil && (f.Status < 400 || f.Status > 599) {
+				err = errors.New("expected an HTTP error status")
+			}
+		case "slow":
+			f.SlowBody, err = time.ParseDuration(value)
+		case "truncate":
+			if value != "" {
+				f.Truncate, err = strconv.ParseBool(value)
+			} else {
+				f.Truncate = true
+			}
+		case "times":
+			f.Times, err = strconv.Atoi(value)
+			if err == nil && f.Times < 0 {
+				err = errors.New("expected a number that is not negative")
+			}
+		default:
+			return f, errors.New("ERROR: invalid fault setting " + s + " - expected path, status, slow, truncate, or times")
+		}
+		if err != nil {
+			return f, errors.New("ERROR: invalid value " + value + " for fault setting " + name + ": " + err.Error())
+		}
+	}
+	if f.Status == 0 && f.SlowBody == 0 && !f.Truncate {
+		return f, errors.New("ERROR: fault " + spec + " does nothing - set status, slow, or truncate")
+	}
+	return f, nil
+}
+
+// fault is a Fault that is in effect, with the number of requests it is still in effect for.
+type fault struct {
+	Fault
+	left int // -1 for every request
+}
+
+// match returns whether the fault applies to the given request, and uses it up once if so.
+func (f *fault) match(r *http.Request) bool {
+	if f.left == 0 || !strings.Contains(r.URL.Path, f.Path) {
+		return false
+	}
+	if f.left > 0 {
+		f.left--
+	}
+	return true
+}
+
+// errTruncated is returned by a faultWriter when it stops writing a response body halfway through.
+var errTruncated = errors.New("response body truncated by fault")
+
+// faultWriter writes a response body slowly, or only half of it, as its fault says.
+type faultWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	f       Fault
+	limit   int64 // how much of the body to write before stopping, or -1 if not known yet
+	written int64
+}
+
+func (fw *faultWriter) Write(p []byte) (int, error) {
+	var err error
+	if fw.f.Truncate {
+		if fw.limit < 0 {
+			cl, perr := strconv.ParseInt(fw.Header().Get("Content-Length"), 10, 64)
+			if perr != nil {
+				cl = int64(len(p))
+			}
+			fw.limit = cl / 2
+		}
+		if fw.written+int64(len(p)) > fw.limit {
+			p = p[:fw.limit-fw.written]
+			err = errTruncated
+		}
+	}
+
+	n := 0
+	if fw.f.SlowBody > 0 {
+		// Write the body in pieces spread over the time given, unless the client goes away.
+		const pieces = 20
+		size := max((len(p)+pieces-1)/pieces, 1)
+		for n < len(p) {
+			m, werr := fw.ResponseWriter.Write(p[n:min(n+size, len(p))])
+			n += m
+			if werr != nil {
+				return n, werr
+			}
+			if f, ok := fw.ResponseWriter.(http.Flusher); ok {
+				f.Flush()
+			}
+			select {
+			case <-fw.r.Context().Done():
+				return n, fw.r.Context().Err()
+			case <-time.After(fw.f.SlowBody / pieces):
+			}
+		}
+	} else {
+		var werr error
+		n, werr = fw.ResponseWriter.Write(p)
+		if werr != nil {
+			return n, werr
+		}
+	}
+	fw.written += int64(n)
+	return n, err
+}
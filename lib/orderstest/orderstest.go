@@ -0,0 +1,497 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package orderstest provides a mock of the SAS Viya Orders API, for testing and demonstrating tools that use it
+// without network access or real credentials. It implements the /mysas/token and /mysas/orders/... endpoints, serving
+// fabricated deployment assets (with the cadence information in sas-bases/checksums.txt), JWT licenses, and
+// certificate zip files, and can be told to fail or misbehave in the ways that the real API can.
+//
+// In a test, start a Server and point the CLI at it:
+//
+//	srv, err := orderstest.NewServer(orderstest.Config{})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer srv.Close()
+//	assetreqs.SetAPIHost(srv.URL)
+//	authn.SetAPIHost(srv.URL)
+//	srv.InjectFault(orderstest.Fault{Path: "deploymentAssets", Status: 503, Times: 1})
+package orderstest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+)
+
+// Cadence is a cadence version of an order, and its releases.
+type Cadence struct {
+	Name        string   // for example stable
+	Version     string   // for example 2026.01
+	Releases    []string // oldest first, so the last one is the latest
+	Unsupported bool     // whether its assets are only served with allowUnsupported=true
+}
+
+// display returns the cadence display name, as found in checksums.txt.
+func (c Cadence) display() string {
+	if strings.EqualFold(c.Name, "lts") {
+		return "LTS " + c.Version
+	}
+	return strings.ToUpper(c.Name[:1]) + c.Name[1:] + " " + c.Version
+}
+
+// Order is an order that the API knows about.
+type Order struct {
+	Number   string
+	Cadences []Cadence
+}
+
+// DefaultOrders returns orders with the given numbers, each with the same few stable and lts cadence versions, one of
+// which (lts 2024.09) is no longer supported.
+func DefaultOrders(orderNums ...string) []Order {
+	orders := []Order{}
+	for _, n := range orderNums {
+		orders = append(orders, Order{Number: n, Cadences: []Cadence{
+			{Name: "stable", Version: "2025.12", Releases: []string{"20251215.1765800000000", "20260105.1767600000000"}},
+			{Name: "stable", Version: "2026.01", Releases: []string{"20260127.1769510312235", "20260215.1771111111111"}},
+			{Name: "lts", Version: "2024.09", Releases: []string{"20240930.1727654400000"}, Unsupported: true},
+			{Name: "lts", Version: "2025.09", Releases: []string{"20250930.1759190400000"}},
+		}})
+	}
+	return orders
+}
+
+// Config provides the settings of the API.
+type Config struct {
+	ClientID     string        // the client ID that is accepted (any credentials are if this and ClientSecret are empty)
+	ClientSecret string        // the client secret that is accepted
+	Orders       []Order       // the orders that the API knows about (default is DefaultOrders("923457"))
+	Faults       []Fault       // the faults that are in effect from the start
+	Expiry       time.Duration // how long licenses and certificates are valid for (default is 365 days)
+	TokenExpiry  time.Duration // how long Bearer tokens are valid for (default is 30 minutes)
+}
+
+// API is a mock of the SAS Viya Orders API. It is an http.Handler.
+type API struct {
+	cfg     Config
+	mux     *http.ServeMux
+	start   time.Time
+	key     []byte // signs licenses
+	ca      *x509.Certificate
+	caKey   *ecdsa.PrivateKey
+	mu      sync.Mutex
+	faults  []*fault
+	tokens  map[string]time.Time                 // Bearer tokens, and when they expire
+	history map[string][]assetreqs.AssetDownload // by order number
+	certs   map[string][]byte                    // by order number, so that they are the same every time
+}
+
+// New creates an API with the given settings.
+func New(cfg Config) (*API, error) {
+	if len(cfg.Orders) == 0 {
+		cfg.Orders = DefaultOrders("923457")
+	}
+	if cfg.Expiry == 0 {
+		cfg.Expiry = 365 * 24 * time.Hour
+	}
+	if cfg.TokenExpiry == 0 {
+		cfg.TokenExpiry = 30 * time.Minute
+	}
+
+	a := &API{
+		cfg:     cfg,
+		start:   time.Now().UTC().Truncate(time.Second),
+		key:     make([]byte, 32),
+		tokens:  map[string]time.Time{},
+		history: map[string][]assetreqs.AssetDownload{},
+		certs:   map[string][]byte{},
+	}
+	_, err := rand.Read(a.key)
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to generate a license signing key failed: " + err.Error())
+	}
+	a.ca, a.caKey, err = newCA(a.start, a.start.Add(2*cfg.Expiry))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range cfg.Faults {
+		a.InjectFault(f)
+	}
+
+	a.mux = http.NewServeMux()
+	a.mux.HandleFunc("POST /mysas/token", a.serveToken)
+	orders := "GET /mysas/orders/{order}/"
+	a.mux.HandleFunc(orders+"certificates", a.serveAsset)
+	a.mux.HandleFunc(orders+"assetHistory", a.serveHistory)
+	a.mux.HandleFunc(orders+"cadenceNames/{name}/deploymentAssets", a.serveAsset)
+	a.mux.HandleFunc(orders+"cadenceNames/{name}/cadenceVersions/{version}/deploymentAssets", a.serveAsset)
+	a.mux.HandleFunc(orders+"cadenceNames/{name}/cadenceVersions/{version}/cadenceReleases/{release}/deploymentAssets",
+		a.serveAsset)
+	a.mux.HandleFunc(orders+"cadenceNames/{name}/cadenceVersions/{version}/license", a.serveAsset)
+	a.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeMessage(w, http.StatusNotFound, "Resource not found")
+	})
+	return a, nil
+}
+
+// ServeHTTP handles a request to the API, with any fault that is in effect for it.
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	defer func() {
+		slog.Info("request", "method", r.Method, "path", r.URL.RequestURI(), "status", sw.status)
+	}()
+
+	a.mu.Lock()
+	var f *Fault
+	for _, ft := range a.faults {
+		if ft.match(r) {
+			f = &ft.Fault
+			break
+		}
+	}
+	a.mu.Unlock()
+
+	switch {
+	case f == nil:
+		a.mux.ServeHTTP(sw, r)
+	case f.Status != 0:
+		if f.Status == http.StatusTooManyRequests {
+			sw.Header().Set("Retry-After", "1")
+		}
+		writeMessage(sw, f.Status, http.StatusText(f.Status))
+	default:
+		a.mux.ServeHTTP(&faultWriter{ResponseWriter: sw, r: r, f: *f, limit: -1}, r)
+	}
+}
+
+// InjectFault puts the given fault into effect, after those that are already in effect. A request is only affected by
+// the first fault that matches it.
+func (a *API) InjectFault(f Fault) {
+	left := f.Times
+	if left == 0 {
+		left = -1
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.faults = append(a.faults, &fault{Fault: f, left: left})
+}
+
+// ClearFaults takes every fault out of effect.
+func (a *API) ClearFaults() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.faults = nil
+}
+
+// AddRelease adds a release to the given cadence of the given order, which becomes its latest release. The cadence is
+// added if the order does not have it yet.
+func (a *API) AddRelease(orderNum, cadenceName, cadenceVer, release string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, o := range a.cfg.Orders {
+		if o.Number != orderNum {
+			continue
+		}
+		for j, c := range o.Cadences {
+			if strings.EqualFold(c.Name, cadenceName) && c.Version == cadenceVer {
+				a.cfg.Orders[i].Cadences[j].Releases = append(c.Releases, release)
+				return nil
+			}
+		}
+		a.cfg.Orders[i].Cadences = append(o.Cadences, Cadence{Name: strings.ToLower(cadenceName),
+			Version: cadenceVer, Releases: []string{release}})
+		return nil
+	}
+	return errors.New("ERROR: order " + orderNum + " not found")
+}
+
+// serveToken exchanges client credentials for a Bearer token, as /mysas/token does.
+func (a *API) serveToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		// Credentials in the Authorization header are URL encoded first.
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if !a.validCreds(id, secret) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	tok := "mock-" + hex.EncodeToString(b)
+	a.mu.Lock()
+	a.tokens[tok] = time.Now().Add(a.cfg.TokenExpiry)
+	a.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": tok,
+		"token_type":   "Bearer",
+		"expires_in":   int(a.cfg.TokenExpiry.Seconds()),
+	})
+}
+
+// validCreds returns whether the given client credentials are accepted.
+func (a *API) validCreds(id, secret string) bool {
+	if a.cfg.ClientID == "" && a.cfg.ClientSecret == "" {
+		return id != "" && secret != ""
+	}
+	return subtle.ConstantTimeCompare([]byte(id), []byte(a.cfg.ClientID)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(a.cfg.ClientSecret)) == 1
+}
+
+// authorize checks that the given request has a valid Bearer token or valid client credentials, and writes an error
+// response if it does not.
+func (a *API) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		a.mu.Lock()
+		exp, known := a.tokens[tok]
+		a.mu.Unlock()
+		if known && time.Now().Before(exp) {
+			return true
+		}
+		if known {
+			writeMessage(w, http.StatusUnauthorized, "Access token expired")
+			return false
+		}
+	} else if a.validCreds(r.Header.Get("ClientId"), r.Header.Get("ClientSecret")) {
+		return true
+	}
+	writeMessage(w, http.StatusUnauthorized, "Invalid credentials")
+	return false
+}
+
+// order returns the order with the number in the path of the given request, or writes an error response if there is
+// no such order.
+func (a *API) order(w http.ResponseWriter, r *http.Request) (Order, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, o := range a.cfg.Orders {
+		if o.Number == r.PathValue("order") {
+			return o, true
+		}
+	}
+	writeMessage(w, http.StatusNotFound, "Order "+r.PathValue("order")+" not found")
+	return Order{}, false
+}
+
+// serveAsset serves the certificates, license, or deployment assets in the path of the given request.
+func (a *API) serveAsset(w http.ResponseWriter, r *http.Request) {
+	if !a.authorize(w, r) {
+		return
+	}
+	o, ok := a.order(w, r)
+	if !ok {
+		return
+	}
+	assetName := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	var c Cadence
+	var release string
+	if assetName != "certificates" {
+		c, ok = findCadence(o, r.PathValue("name"), r.PathValue("version"))
+		if !ok {
+			writeMessage(w, http.StatusNotFound, "Cadence "+strings.TrimSpace(r.PathValue("name")+" "+
+				r.PathValue("version"))+" not found for order "+o.Number)
+			return
+		}
+	}
+	if c.Unsupported && r.URL.Query().Get("allowUnsupported") != "true" {
+		writeMessage(w, http.StatusBadRequest, "Cadence "+c.display()+" is no longer supported")
+		return
+	}
+	if assetName == "deploymentAssets" {
+		release = c.Releases[len(c.Releases)-1]
+		if rel := r.PathValue("release"); rel != "" {
+			release = ""
+			for _, cr := range c.Releases {
+				if cr == rel {
+					release = cr
+				}
+			}
+			if release == "" {
+				writeMessage(w, http.StatusNotFound, "Cadence release "+rel+" not found for "+c.display())
+				return
+			}
+		}
+	}
+
+	var body []byte
+	var contentType string
+	var err error
+	switch assetName {
+	case "certificates":
+		body, err = a.certificates(o.Number)
+		contentType = "application/zip"
+	case "license":
+		body, err = license(o.Number, c, a.key, a.start, a.start.Add(a.cfg.Expiry))
+		contentType = "application/jwt"
+	default:
+		body, err = deploymentAssets(o.Number, c, release)
+		contentType = "application/gzip"
+	}
+	if err != nil {
+		writeMessage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	a.mu.Lock()
+	a.history[o.Number] = append(a.history[o.Number], assetreqs.AssetDownload{
+		DownloadDate:   time.Now().UTC().Truncate(time.Second),
+		AssetType:      assetName,
+		CadenceName:    c.Name,
+		CadenceVersion: c.Version,
+		CadenceRelease: release,
+		User:           "orderstest",
+	})
+	a.mu.Unlock()
+
+	// The same asset always has the same contents, so clients can cache it.
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", a.start.Format(http.TimeFormat))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": fileName(o.Number, assetName, c, release, a.start)}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	_, _ = w.Write(body)
+}
+
+// serveHistory serves the asset history of the order in the path of the given request.
+func (a *API) serveHistory(w http.ResponseWriter, r *http.Request) {
+	if !a.authorize(w, r) {
+		return
+	}
+	o, ok := a.order(w, r)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	h := assetreqs.AssetHistory{OrderNumber: o.Number, Downloads: append([]assetreqs.AssetDownload{},
+		a.history[o.Number]...)}
+	a.mu.Unlock()
+	writeJSON(w, http.StatusOK, h)
+}
+
+// certificates returns the certificates of the given order, creating them the first time.
+func (a *API) certificates(orderNum string) ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if b, ok := a.certs[orderNum]; ok {
+		return b, nil
+	}
+	b, err := certificates(orderNum, a.ca, a.caKey, a.start, a.start.Add(a.cfg.Expiry))
+	if err != nil {
+		return nil, err
+	}
+	a.certs[orderNum] = b
+	return b, nil
+}
+
+// findCadence returns the cadence of the given order with the given name and version, or the latest version of the
+// given name if no version is given.
+func findCadence(o Order, name, version string) (c Cadence, found bool) {
+	for _, oc := range o.Cadences {
+		if !strings.EqualFold(oc.Name, name) || len(oc.Releases) == 0 {
+			continue
+		}
+		if version == "" && (!found || oc.Version > c.Version) || version != "" && oc.Version == version {
+			c, found = oc, true
+		}
+	}
+	return c, found
+}
+
+// Server is an API served by an httptest.Server on a local address, for tests.
+type Server struct {
+	*httptest.Server
+	*API
+}
+
+// NewServer starts a Server with an API with the given settings. Call Close to stop it.
+func NewServer(cfg Config) (*Server, error) {
+	a, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{Server: httptest.NewServer(a), API: a}, nil
+}
+
+// ListenAndServe serves the given API on the given address until the given context is done.
+func ListenAndServe(ctx context.Context, addr string, a *API) error {
+	srv := &http.Server{Addr: addr, Handler: a, ReadHeaderTimeout: 30 * time.Second}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		return errors.New("ERROR: mock server failed: " + err.Error())
+	case <-ctx.Done():
+	}
+	err := srv.Shutdown(context.Background())
+	if err != nil {
+		return errors.New("ERROR: mock server shutdown failed: " + err.Error())
+	}
+	return nil
+}
+
+// statusWriter remembers the status of a response, for logging.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeMessage writes an error response with the given message, in the form that the API uses.
+func writeMessage(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": strings.TrimPrefix(message, "ERROR: ")})
+}
+
+// writeJSON writes a response with the given value as JSON.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	b, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(b)+1))
+	w.WriteHeader(status)
+	_, _ = w.Write(append(b, '\n'))
+}
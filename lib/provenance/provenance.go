@@ -0,0 +1,171 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package provenance provides the metadata files that are saved next to downloaded order assets, so that where an
+// asset came from is known for as long as the asset is kept.
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Suffix is added to the name of an asset file to name its metadata file.
+const Suffix string = ".meta.json"
+
+// Metadata describes where a downloaded order asset came from.
+type Metadata struct {
+	OrderNumber    string    `json:"orderNumber" yaml:"orderNumber"`
+	AssetName      string    `json:"assetName" yaml:"assetName"`
+	AssetFile      string    `json:"assetFile" yaml:"assetFile"` // the name of the asset file, in the same directory
+	AssetReqURL    string    `json:"assetReqURL" yaml:"assetReqURL"`
+	Cadence        string    `json:"cadence,omitempty" yaml:"cadence,omitempty"`
+	CadenceRelease string    `json:"cadenceRelease,omitempty" yaml:"cadenceRelease,omitempty"`
+	DownloadedAt   time.Time `json:"downloadedAt" yaml:"downloadedAt"`
+	Size           int64     `json:"size" yaml:"size"`
+	SHA256         string    `json:"sha256" yaml:"sha256"` // hex digest of the contents
+	CLIVersion     string    `json:"cliVersion" yaml:"cliVersion"`
+}
+
+// Asset is a downloaded order asset, as described by its metadata file.
+type Asset struct {
+	Metadata `yaml:",inline"`
+	// Status is ok if the asset file is as it was downloaded, modified if its contents have changed since, or missing
+	// if it is gone.
+	Status string `json:"status" yaml:"status"`
+}
+
+// File returns the name of the metadata file for the given asset file.
+func File(assetFile string) string {
+	return assetFile + Suffix
+}
+
+// Write saves the given metadata for the given asset file, next to it, readable by whoever can read the asset file.
+func Write(assetFile string, m Metadata) error {
+	m.AssetFile = filepath.Base(assetFile)
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return errors.New("ERROR: json.MarshalIndent() returned: " + err.Error())
+	}
+	// Write the metadata next to where it goes, so that readers never see a partial file.
+	dir := filepath.Dir(assetFile)
+	f, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return errors.New("ERROR: attempt to create file in " + dir + " failed: " + err.Error())
+	}
+	// Temporary files are only readable by their owner.
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(assetFile); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	err = f.Chmod(mode)
+	if err == nil {
+		_, err = f.Write(append(b, '\n'))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), File(assetFile))
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return errors.New("ERROR: attempt to write metadata file " + File(assetFile) + " failed: " + err.Error())
+	}
+	return nil
+}
+
+// Read reads the given metadata file.
+func Read(file string) (Metadata, error) {
+	var m Metadata
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return m, errors.New("ERROR: attempt to read metadata file " + file + " failed: " + err.Error())
+	}
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return m, errors.New("ERROR: attempt to parse metadata file " + file + " failed: " + err.Error())
+	}
+	return m, nil
+}
+
+// Move moves the metadata file for the given asset file, if there is one, to go with the asset file at its new
+// location.
+func Move(assetFile, dest string) error {
+	if _, err := os.Stat(File(assetFile)); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	m, err := Read(File(assetFile))
+	if err != nil {
+		return err
+	}
+	err = Write(dest, m)
+	if err != nil {
+		return err
+	}
+	_ = os.Remove(File(assetFile))
+	return nil
+}
+
+// List returns the assets in the given directory that have metadata files, most recently downloaded first. The
+// contents of each asset file are checked against its metadata.
+func List(dir string) ([]Asset, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+Suffix))
+	if err != nil {
+		return nil, errors.New("ERROR: attempt to list metadata files in " + dir + " failed: " + err.Error())
+	}
+	assets := []Asset{}
+	for _, file := range files {
+		m, err := Read(file)
+		if err != nil {
+			return nil, err
+		}
+		// A metadata file goes with the asset file that it is named after, even if they were renamed together.
+		m.AssetFile = strings.TrimSuffix(filepath.Base(file), Suffix)
+		status, err := check(filepath.Join(dir, m.AssetFile), m)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, Asset{Metadata: m, Status: status})
+	}
+	sort.SliceStable(assets, func(i, j int) bool { return assets[i].DownloadedAt.After(assets[j].DownloadedAt) })
+	return assets, nil
+}
+
+// check returns the status of the given asset file: whether it is still as its metadata describes it.
+func check(assetFile string, m Metadata) (string, error) {
+	if _, err := os.Stat(assetFile); errors.Is(err, os.ErrNotExist) {
+		return "missing", nil
+	}
+	size, sum, err := digest(assetFile)
+	if err != nil {
+		return "", err
+	}
+	if size != m.Size || sum != m.SHA256 {
+		return "modified", nil
+	}
+	return "ok", nil
+}
+
+// digest returns the size of the given file and the hex SHA-256 digest of its contents.
+func digest(file string) (int64, string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, "", errors.New("ERROR: attempt to open " + file + " failed: " + err.Error())
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", errors.New("ERROR: attempt to read " + file + " failed: " + err.Error())
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
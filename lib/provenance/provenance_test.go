@@ -0,0 +1,81 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWriteAndList(t *testing.T) {
+	dir := t.TempDir()
+	contents := []byte("deployment assets")
+	sum := sha256.Sum256(contents)
+	var assets []string
+	for i, mode := range []os.FileMode{0644, 0600} {
+		asset := filepath.Join(dir, "SASViyaV4_923457_"+string(rune('a'+i))+".tgz")
+		if err := os.WriteFile(asset, contents, mode); err != nil {
+			t.Fatal(err)
+		}
+		// Files are created with the mode given, less the umask.
+		if err := os.Chmod(asset, mode); err != nil {
+			t.Fatal(err)
+		}
+		err := Write(asset, Metadata{OrderNumber: "923457", AssetName: "deploymentAssets",
+			DownloadedAt: time.Now().Add(time.Duration(i) * time.Minute), Size: int64(len(contents)),
+			SHA256: hex.EncodeToString(sum[:])})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(File(asset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runtime.GOOS != "windows" && fi.Mode().Perm() != mode {
+			t.Errorf("the metadata file of an asset with mode %v has mode %v", mode, fi.Mode().Perm())
+		}
+		assets = append(assets, asset)
+	}
+
+	// The asset files change, and move, after they are downloaded.
+	if err := os.WriteFile(assets[0], []byte("modified"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	moved := filepath.Join(dir, "moved.tgz")
+	if err := os.Rename(assets[1], moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := Move(assets[1], moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "gone.tgz"+Suffix), []byte(`{"orderNumber":"923457"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, a := range list {
+		got[a.AssetFile] = a.Status
+	}
+	want := map[string]string{filepath.Base(assets[0]): "modified", "moved.tgz": "ok", "gone.tgz": "missing"}
+	if len(got) != len(want) {
+		t.Errorf("List returned %v, want %v", got, want)
+	}
+	for file, status := range want {
+		if got[file] != status {
+			t.Errorf("List returned status %q for %s, want %q", got[file], file, status)
+		}
+	}
+	if len(list) > 0 && list[0].AssetFile != "moved.tgz" {
+		t.Errorf("List returned %s first, not the most recently downloaded asset", list[0].AssetFile)
+	}
+}
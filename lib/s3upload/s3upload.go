@@ -0,0 +1,211 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package s3upload provides an uploader that streams order assets to S3-compatible object storage as they are
+// downloaded.
+package s3upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// partSize is the size of the parts that an asset of unknown size is uploaded in.
+const partSize uint64 = 16 * 1024 * 1024
+
+// sha256Meta is the user metadata key that holds the SHA-256 digest of an uploaded asset.
+const sha256Meta string = "Sha256"
+
+// errIdentical stops an upload whose contents are identical to those of the object already at its key.
+var errIdentical = errors.New("an identical object already exists")
+
+// errDigestMismatch stops an upload whose contents do not have the digest that they were expected to have.
+var errDigestMismatch = errors.New("the asset does not have the expected digest")
+
+// Config provides the settings used to connect to the object storage service.
+type Config struct {
+	Endpoint     string // host[:port] of the service (default is s3.amazonaws.com)
+	Region       string
+	AccessKeyID  string // if not set, the standard AWS and MinIO environment variables and files are used
+	SecretKey    string
+	SessionToken string
+	PathStyle    bool // address buckets as endpoint/bucket rather than bucket.endpoint
+	Insecure     bool // use HTTP rather than HTTPS
+}
+
+// Uploader uploads assets to a bucket, under a prefix, in object storage.
+type Uploader struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	upload *upload
+}
+
+// upload is an upload that is in progress.
+type upload struct {
+	key       string
+	pw        *io.PipeWriter
+	hash      hash.Hash
+	done      chan error
+	digest    string // SHA-256 digest of the asset, if it was known when the upload began
+	existing  string // SHA-256 digest of the object already at the key, if any
+	identical bool   // whether the object already at the key was known to be identical when the upload began
+}
+
+// New creates an Uploader for the given s3://bucket/prefix destination.
+func New(dest string, cfg Config) (*Uploader, error) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, errors.New("ERROR: invalid upload destination " + dest + " - expected s3://bucket/prefix")
+	}
+
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+	// Accept an endpoint given as a URL, too.
+	if e, err := url.Parse(endpoint); err == nil && e.Host != "" {
+		endpoint = e.Host
+		if e.Scheme == "http" {
+			cfg.Insecure = true
+		}
+	}
+
+	var creds *credentials.Credentials
+	if cfg.AccessKeyID != "" {
+		creds = credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretKey, cfg.SessionToken)
+	} else {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.EnvMinio{},
+			&credentials.FileAWSCredentials{},
+		})
+	}
+
+	lookup := minio.BucketLookupAuto
+	if cfg.PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:        creds,
+		Secure:       !cfg.Insecure,
+		Region:       cfg.Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, errors.New("ERROR: setup of object storage client failed: " + err.Error())
+	}
+
+	return &Uploader{client: client, bucket: u.Host, prefix: strings.Trim(u.Path, "/")}, nil
+}
+
+// Begin starts uploading the asset with the given file name, size (-1 if not known), and SHA-256 digest (empty if not
+// known), with the given metadata and the digest stored with the uploaded object, and returns a writer for its
+// contents. If the digest is known, and the object already at the key has the same digest, nothing is uploaded. The
+// upload is not complete until Commit is called.
+func (u *Uploader) Begin(fileName string, size int64, digest string, meta map[string]string) (io.Writer, error) {
+	key := path.Join(u.prefix, path.Base(strings.ReplaceAll(fileName, "\\", "/")))
+	ctx := context.Background()
+
+	// Find out if there is already an object at the key, so that an identical one is left as it is.
+	var existing string
+	info, err := u.client.StatObject(ctx, u.bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		existing = info.Metadata.Get("X-Amz-Meta-" + sha256Meta)
+	} else if minio.ToErrorResponse(err).StatusCode != http.StatusNotFound {
+		return nil, errors.New("ERROR: attempt to check for existing object " + u.Location(key) + " failed: " + err.Error())
+	}
+	if digest != "" && digest == existing {
+		u.upload = &upload{key: key, digest: digest, existing: existing, identical: true}
+		return io.Discard, nil
+	}
+
+	// The digest is stored with the object when it is known before the upload, as the object's metadata cannot be
+	// changed once it is uploaded without copying it.
+	m := map[string]string{}
+	for k, v := range meta {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if digest != "" {
+		m[sha256Meta] = digest
+	}
+
+	pr, pw := io.Pipe()
+	up := &upload{key: key, pw: pw, hash: sha256.New(), done: make(chan error, 1), digest: digest, existing: existing}
+	go func() {
+		_, err := u.client.PutObject(ctx, u.bucket, key, pr, size,
+			minio.PutObjectOptions{PartSize: partSize, UserMetadata: m})
+		// Make sure that writes to the pipe fail rather than block if the upload stopped early.
+		_ = pr.CloseWithError(err)
+		up.done <- err
+	}()
+	u.upload = up
+
+	return io.MultiWriter(pw, up.hash), nil
+}
+
+// Commit finishes the upload that was begun. If the object already at the key has the same digest as the asset, the
+// upload is abandoned and the object is left as it is. Commit returns the location of the object and whether it was
+// left as it is.
+func (u *Uploader) Commit() (location string, unchanged bool, err error) {
+	up := u.upload
+	if up == nil {
+		return "", false, errors.New("ERROR: no upload to commit")
+	}
+	u.upload = nil
+	location = u.Location(up.key)
+	if up.identical {
+		return location, true, nil
+	}
+
+	digest := hex.EncodeToString(up.hash.Sum(nil))
+	if digest == up.existing {
+		// Failing the upload keeps the incomplete upload from replacing the existing object.
+		_ = up.pw.CloseWithError(errIdentical)
+		<-up.done
+		return location, true, nil
+	}
+	if up.digest != "" && digest != up.digest {
+		// The object would be stored with the wrong digest.
+		_ = up.pw.CloseWithError(errDigestMismatch)
+		<-up.done
+		return location, false, errors.New("ERROR: upload to " + location + " failed: the SHA-256 digest of the " +
+			"asset is " + digest + ", not " + up.digest)
+	}
+
+	_ = up.pw.Close()
+	if err := <-up.done; err != nil {
+		return location, false, errors.New("ERROR: upload to " + location + " failed: " + err.Error())
+	}
+	return location, false, nil
+}
+
+// Abort abandons the upload that was begun.
+func (u *Uploader) Abort() {
+	if up := u.upload; up != nil {
+		u.upload = nil
+		if !up.identical {
+			_ = up.pw.CloseWithError(errors.New("upload aborted"))
+			<-up.done
+		}
+	}
+}
+
+// Location returns the s3:// URL of the object with the given key.
+func (u *Uploader) Location(key string) string {
+	return "s3://" + u.bucket + "/" + key
+}
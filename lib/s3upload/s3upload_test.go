@@ -0,0 +1,255 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package s3upload
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// object is an object stored by stubS3.
+type object struct {
+	body []byte
+	meta map[string]string // user metadata, by the name that it was sent with
+}
+
+// stubS3 is just enough of an S3-compatible object storage service for an Uploader: objects can be looked at and
+// uploaded in one piece.
+type stubS3 struct {
+	mu        sync.Mutex
+	objects   map[string]object // by path: /bucket/key
+	puts      int               // the number of uploads that were started
+	putStatus int               // if set, uploads fail with this status
+}
+
+func (s *stubS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodHead:
+		obj, ok := s.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		for k, v := range obj.meta {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.body)))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	case http.MethodPut:
+		if r.URL.RawQuery != "" || r.Header.Get("X-Amz-Copy-Source") != "" {
+			http.Error(w, "only uploads in one piece are supported", http.StatusNotImplemented)
+			return
+		}
+		s.puts++
+		if s.putStatus != 0 {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(s.putStatus)
+			_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code>`+
+				`<Message>Access Denied</Message></Error>`)
+			return
+		}
+		body, err := readBody(r)
+		if err != nil {
+			// The upload was abandoned, so the object is not replaced.
+			return
+		}
+		meta := map[string]string{}
+		for k := range r.Header {
+			if strings.HasPrefix(k, "X-Amz-Meta-") {
+				meta[k] = r.Header.Get(k)
+			}
+		}
+		s.objects[r.URL.Path] = object{body: body, meta: meta}
+		w.Header().Set("ETag", `"etag"`)
+	default:
+		http.Error(w, "not supported", http.StatusNotImplemented)
+	}
+}
+
+// object returns the object at the given path, if there is one.
+func (s *stubS3) object(path string) (object, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	obj, ok := s.objects[path]
+	return obj, ok
+}
+
+// uploads returns the number of uploads that were started.
+func (s *stubS3) uploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.puts
+}
+
+// readBody reads the body of an upload, which is sent in signed chunks over HTTP.
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	var body []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		chunk := make([]byte, size+2) // followed by \r\n
+		if _, err = io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body, nil
+		}
+		body = append(body, chunk[:size]...)
+	}
+}
+
+// newUploader starts a stub object storage service, and returns it and an Uploader to its assets bucket.
+func newUploader(t *testing.T) (*stubS3, *Uploader) {
+	t.Helper()
+	s3 := &stubS3{objects: map[string]object{}}
+	srv := httptest.NewServer(s3)
+	t.Cleanup(srv.Close)
+	u, err := New("s3://assets/viya/923457", Config{Endpoint: srv.URL, Region: "us-east-1", AccessKeyID: "id",
+		SecretKey: "secret", PathStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s3, u
+}
+
+// uploadAsset uploads the given contents as an asset with the given digest, known before the upload, and returns the
+// results of Commit.
+func uploadAsset(t *testing.T, u *Uploader, contents, digest string) (string, bool, error) {
+	t.Helper()
+	w, err := u.Begin("/tmp/SASViyaV4_923457_certs.zip", int64(len(contents)), digest,
+		map[string]string{"Order-Number": "923457", "Asset-Name": "certificates", "Cadence": ""})
+	if err != nil {
+		t.Fatalf("Begin returned %v", err)
+	}
+	if _, err = io.WriteString(w, contents); err != nil {
+		t.Fatalf("write to the upload returned %v", err)
+	}
+	return u.Commit()
+}
+
+func digestOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestUploadNewObject(t *testing.T) {
+	const key = "/assets/viya/923457/SASViyaV4_923457_certs.zip"
+	for _, digest := range []string{"", digestOf("certificates")} {
+		s3, u := newUploader(t)
+		loc, unchanged, err := uploadAsset(t, u, "certificates", digest)
+		if err != nil || unchanged || loc != "s3://assets/viya/923457/SASViyaV4_923457_certs.zip" {
+			t.Errorf("upload of a new object with digest %q returned %s, %t, %v", digest, loc, unchanged, err)
+			continue
+		}
+		obj, ok := s3.object(key)
+		if !ok || string(obj.body) != "certificates" {
+			t.Errorf("upload of a new object with digest %q stored %q", digest, obj.body)
+		}
+		// The metadata is sent with the upload itself, and metadata without a value is left out.
+		want := map[string]string{"X-Amz-Meta-Order-Number": "923457", "X-Amz-Meta-Asset-Name": "certificates"}
+		if digest != "" {
+			want["X-Amz-Meta-Sha256"] = digest
+		}
+		if len(obj.meta) != len(want) {
+			t.Errorf("upload of a new object with digest %q stored the metadata %v, want %v", digest, obj.meta, want)
+		}
+		for k, v := range want {
+			if obj.meta[k] != v {
+				t.Errorf("upload of a new object with digest %q stored %s: %q, want %q", digest, k, obj.meta[k], v)
+			}
+		}
+	}
+}
+
+func TestUploadIdenticalObject(t *testing.T) {
+	const key = "/assets/viya/923457/SASViyaV4_923457_certs.zip"
+	existing := object{body: []byte("certificates"),
+		meta: map[string]string{"X-Amz-Meta-Sha256": digestOf("certificates"), "X-Amz-Meta-Order-Number": "923457"}}
+
+	// When the digest is known up front, nothing is uploaded.
+	s3, u := newUploader(t)
+	s3.objects[key] = existing
+	_, unchanged, err := uploadAsset(t, u, "certificates", digestOf("certificates"))
+	if err != nil || !unchanged || s3.uploads() != 0 {
+		t.Errorf("upload of an identical object with a known digest returned %t, %v after %d uploads", unchanged, err,
+			s3.uploads())
+	}
+
+	// When it is not, the upload is abandoned once the digest is known, and the object is left as it is.
+	s3, u = newUploader(t)
+	s3.objects[key] = existing
+	_, unchanged, err = uploadAsset(t, u, "certificates", "")
+	if err != nil || !unchanged {
+		t.Errorf("upload of an identical object without a known digest returned %t, %v", unchanged, err)
+	}
+	if obj, _ := s3.object(key); obj.meta["X-Amz-Meta-Order-Number"] != "923457" || len(obj.meta) != 2 {
+		t.Errorf("upload of an identical object without a known digest replaced it: %v", obj.meta)
+	}
+
+	// An object with different contents is replaced.
+	s3, u = newUploader(t)
+	s3.objects[key] = existing
+	_, unchanged, err = uploadAsset(t, u, "new certificates", digestOf("new certificates"))
+	if obj, _ := s3.object(key); err != nil || unchanged || !bytes.Equal(obj.body, []byte("new certificates")) {
+		t.Errorf("upload of a changed object returned %t, %v and stored %q", unchanged, err, obj.body)
+	}
+}
+
+func TestUploadFailure(t *testing.T) {
+	const key = "/assets/viya/923457/SASViyaV4_923457_certs.zip"
+
+	s3, u := newUploader(t)
+	s3.putStatus = http.StatusForbidden
+	if _, _, err := uploadAsset(t, u, "certificates", ""); err == nil {
+		t.Error("upload that the service refused returned no error")
+	}
+
+	// An asset that is not what it was expected to be is not stored with the wrong digest.
+	s3, u = newUploader(t)
+	if _, _, err := uploadAsset(t, u, "certificates", digestOf("other certificates")); err == nil {
+		t.Error("upload of an asset with the wrong digest returned no error")
+	}
+	if obj, ok := s3.object(key); ok {
+		t.Errorf("upload of an asset with the wrong digest stored %q", obj.body)
+	}
+
+	// An aborted upload stores nothing.
+	s3, u = newUploader(t)
+	w, err := u.Begin("SASViyaV4_923457_certs.zip", 12, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(w, "certif")
+	u.Abort()
+	if _, ok := s3.object(key); ok {
+		t.Error("aborted upload stored the object")
+	}
+	if _, _, err = u.Commit(); err == nil {
+		t.Error("Commit after Abort returned no error")
+	}
+}
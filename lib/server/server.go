@@ -0,0 +1,333 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package server provides an HTTP server that exposes the order asset requests as a REST API, so that tools can get
+// order assets without holding the SAS Viya Orders API credentials.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/apierrors"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/metrics"
+	"github.com/sassoftware/viya4-orders-cli/lib/notify"
+)
+
+// NewReqFunc returns an AssetReq for the given asset name, order number, cadence name, cadence version, and cadence
+// release, with the credentials and options of the server.
+type NewReqFunc func(assetName, orderNum, cadenceName, cadenceVer, cadenceRel string) (assetreqs.AssetReq, error)
+
+// Config provides the settings of the server.
+type Config struct {
+	Addr         string   // the address to listen on, for example :8080
+	Tokens       []string // the static tokens that callers can authenticate with
+	CertFile     string   // the certificate and key to serve HTTPS with
+	KeyFile      string
+	ClientCAFile string // the CA certificates that client certificates must be signed by, for mTLS
+	NewReq       NewReqFunc
+	Notifier     *notify.Notifier // if set, told when a license or certificates that were sent expire soon
+}
+
+// Server serves order assets over HTTP.
+type Server struct {
+	cfg Config
+	srv *http.Server
+}
+
+// New creates a Server with the given settings. At least one way for callers to authenticate must be set up.
+func New(cfg Config) (*Server, error) {
+	if len(cfg.Tokens) == 0 && cfg.ClientCAFile == "" {
+		return nil, errors.New("ERROR: no way for callers to authenticate - set serveTokens or a client CA")
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("ERROR: both a TLS certificate and a TLS key are needed to serve HTTPS")
+	}
+	if cfg.ClientCAFile != "" && cfg.CertFile == "" {
+		return nil, errors.New("ERROR: a TLS certificate and key are needed to authenticate callers with client certificates")
+	}
+
+	s := &Server{cfg: cfg}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.Handle("GET /orders/{order}/{asset}", s.authenticate(http.HandlerFunc(s.serveAsset)))
+	mux.Handle("GET /metrics", s.authenticate(metrics.Handler()))
+
+	s.srv = &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, errors.New("ERROR: attempt to read client CA file " + cfg.ClientCAFile + " failed: " + err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("ERROR: no certificates found in client CA file " + cfg.ClientCAFile)
+		}
+		// Callers with a token do not need a client certificate.
+		auth := tls.RequireAndVerifyClientCert
+		if len(cfg.Tokens) > 0 {
+			auth = tls.VerifyClientCertIfGiven
+		}
+		s.srv.TLSConfig = &tls.Config{ClientCAs: pool, ClientAuth: auth, MinVersion: tls.VersionTLS12}
+	}
+
+	return s, nil
+}
+
+// ListenAndServe serves requests until the given context is done, and then shuts the server down, letting the
+// requests in progress finish.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	errc := make(chan error, 1)
+	go func() {
+		if s.cfg.CertFile != "" {
+			errc <- s.srv.ListenAndServeTLS(s.cfg.CertFile, s.cfg.KeyFile)
+		} else {
+			errc <- s.srv.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errc:
+		return errors.New("ERROR: server failed: " + err.Error())
+	case <-ctx.Done():
+	}
+	slog.Info("shutting down")
+	err := s.srv.Shutdown(context.Background())
+	if err != nil {
+		return errors.New("ERROR: server shutdown failed: " + err.Error())
+	}
+	return nil
+}
+
+// authenticate only passes on requests from callers with a verified client certificate or a valid token.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := s.caller(r)
+		if caller == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="viya4-orders-cli"`)
+			writeError(w, http.StatusUnauthorized, "a valid token or client certificate is required")
+			slog.Info("request", "remote", r.RemoteAddr, "method", r.Method, "path", r.URL.Path,
+				"status", http.StatusUnauthorized)
+			return
+		}
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		slog.Info("request", "remote", r.RemoteAddr, "caller", caller, "method", r.Method, "path", r.URL.RequestURI(),
+			"status", sw.status, "duration", time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// caller returns who made the given request, or an empty string if they could not be authenticated.
+func (s *Server) caller(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return "cert " + r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	if tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		for i, t := range s.cfg.Tokens {
+			if subtle.ConstantTimeCompare([]byte(tok), []byte(t)) == 1 {
+				return "token " + strconv.Itoa(i+1)
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	// orderPattern matches order numbers.
+	orderPattern = regexp.MustCompile(`^[0-9]+$`)
+	// cadencePattern matches cadence names, versions, and releases, which each become a segment of the path of the
+	// SAS Viya Orders API request.
+	cadencePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// serveAsset streams the requested order asset to the caller.
+func (s *Server) serveAsset(w http.ResponseWriter, r *http.Request) {
+	order := r.PathValue("order")
+	asset := r.PathValue("asset")
+	q := r.URL.Query()
+	cadence, version, release := q.Get("cadence"), q.Get("version"), q.Get("release")
+
+	if !orderPattern.MatchString(order) {
+		writeError(w, http.StatusBadRequest, "invalid order number "+order+" - expected digits")
+		return
+	}
+	for name, value := range map[string]string{"cadence": cadence, "version": version, "release": release} {
+		if value != "" && !cadencePattern.MatchString(value) {
+			writeError(w, http.StatusBadRequest, "invalid value "+value+" for the "+name+" query parameter")
+			return
+		}
+	}
+
+	switch asset {
+	case "deploymentAssets":
+		if cadence == "" {
+			writeError(w, http.StatusBadRequest, "the cadence query parameter is required")
+			return
+		}
+		if release != "" && version == "" {
+			writeError(w, http.StatusBadRequest, "the version query parameter is required with release")
+			return
+		}
+	case "license":
+		if cadence == "" || version == "" {
+			writeError(w, http.StatusBadRequest, "the cadence and version query parameters are required")
+			return
+		}
+		release = ""
+	case "certificates", "assetHistory":
+		cadence, version, release = "", "", ""
+	default:
+		writeError(w, http.StatusNotFound, "unknown asset "+asset+
+			" - expected deploymentAssets, license, certificates, or assetHistory")
+		return
+	}
+
+	// Getting a request ready can fail at the SAS Viya Orders API too, when it asks for a Bearer token.
+	ar, err := s.cfg.NewReq(asset, order, cadence, version, release)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	// Keep a copy of a license or certificates as they are sent, to record when they expire.
+	aw := &assetWriter{w: w, keep: asset == "license" || asset == "certificates"}
+	output, err := ar.WithWriter(aw).Fetch()
+	if err != nil {
+		if !aw.started {
+			writeAPIError(w, err)
+			return
+		}
+		// Part of the asset has been sent, so the only way to tell the caller is to cut the response short.
+		slog.Error("asset request failed after part of the asset was sent", "order", order, "asset", asset,
+			"error", err)
+		panic(http.ErrAbortHandler)
+	}
+
+	if aw.keep {
+		exp, err := notify.ExpiryOf(asset, aw.kept.Bytes(), "the "+asset+" for order "+order)
+		if err != nil {
+			slog.Warn("could not determine when the asset expires", "order", order, "asset", asset, "error", err)
+			return
+		}
+		if !exp.IsZero() {
+			metrics.SetExpiry(order, asset, exp)
+		}
+		if s.cfg.Notifier != nil {
+			s.cfg.Notifier.Expires(output, exp)
+		}
+	}
+}
+
+// writeAPIError sends the given error, which a request to the SAS Viya Orders API failed with, to the caller, with
+// the status for it and, if the API said how long to wait before trying again, a Retry-After header.
+func writeAPIError(w http.ResponseWriter, err error) {
+	var ae *apierrors.Error
+	if errors.As(err, &ae) && ae.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(ae.RetryAfter.Round(time.Second).Seconds())))
+	}
+	writeError(w, errorStatus(err), err.Error())
+}
+
+// errorStatus returns the status to send the caller for a request that failed with the given error. The credentials
+// of the server not being accepted is not the fault of the caller, who has authenticated, so it is sent as 403
+// Forbidden, rather than 401 Unauthorized. Requests to the SAS Viya Orders API that time out are sent as 504 Gateway
+// Timeout, and other failures of the API, and failures to reach it, as 502 Bad Gateway.
+func errorStatus(err error) int {
+	var ae *apierrors.Error
+	var te *apiclient.TimeoutError
+	switch {
+	case errors.Is(err, apierrors.ErrOrderNotFound), errors.Is(err, apierrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apierrors.ErrUnauthorized):
+		return http.StatusForbidden
+	case errors.Is(err, apierrors.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, apierrors.ErrCadenceUnsupported):
+		return http.StatusBadRequest
+	case errors.As(err, &ae) && ae.StatusCode >= 400 && ae.StatusCode < 500:
+		return http.StatusBadRequest
+	case errors.As(err, &te):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// maxKept is the most of an asset that an assetWriter keeps a copy of. Licenses and certificates are much smaller.
+const maxKept int = 16 << 20
+
+// assetWriter streams an asset to an HTTP response, keeping a copy of it if asked to.
+type assetWriter struct {
+	w       http.ResponseWriter
+	started bool
+	keep    bool
+	kept    bytes.Buffer
+}
+
+// BeginAsset sets the headers of the response for an asset with the given file name and size.
+func (aw *assetWriter) BeginAsset(fileName string, size int64) {
+	ct := mime.TypeByExtension(filepath.Ext(fileName))
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	aw.w.Header().Set("Content-Type", ct)
+	aw.w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	if size >= 0 {
+		aw.w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+}
+
+func (aw *assetWriter) Write(p []byte) (int, error) {
+	aw.started = true
+	if aw.keep {
+		if aw.kept.Len()+len(p) > maxKept {
+			aw.keep = false
+			aw.kept = bytes.Buffer{}
+		} else {
+			aw.kept.Write(p)
+		}
+	}
+	return aw.w.Write(p)
+}
+
+// statusWriter remembers the status of a response, for logging.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+// writeError sends the given error message to the caller as JSON.
+func writeError(w http.ResponseWriter, status int, message string) {
+	b, _ := json.Marshal(struct {
+		Error string `json:"error"`
+	}{strings.TrimSpace(strings.TrimPrefix(message, "ERROR: "))})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(b, '\n'))
+}
@@ -0,0 +1,201 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sassoftware/viya4-orders-cli/lib/apiclient"
+	"github.com/sassoftware/viya4-orders-cli/lib/assetreqs"
+	"github.com/sassoftware/viya4-orders-cli/lib/authn"
+	"github.com/sassoftware/viya4-orders-cli/lib/notify"
+	"github.com/sassoftware/viya4-orders-cli/lib/orderstest"
+)
+
+func TestServeAssetStatus(t *testing.T) {
+	api, err := orderstest.NewServer(orderstest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.Close()
+	assetreqs.SetAPIHost(api.URL)
+	defer assetreqs.SetAPIHost("")
+
+	srv, err := New(Config{
+		Tokens: []string{"secret"},
+		NewReq: func(assetName, orderNum, cadenceName, cadenceVer, cadenceRel string) (assetreqs.AssetReq, error) {
+			return assetreqs.New("apim", "", "id", "secret", assetName, orderNum, cadenceName, cadenceVer, cadenceRel,
+				"", "", "json", false), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path   string
+		fault  *orderstest.Fault
+		status int
+	}{
+		{path: "/orders/923457/certificates", status: http.StatusOK},
+		{path: "/orders/923457/deploymentAssets?cadence=stable&version=2026.01", status: http.StatusOK},
+		{path: "/orders/92x457/certificates", status: http.StatusBadRequest},
+		{path: "/orders/923457/deploymentAssets?cadence=..", status: http.StatusBadRequest},
+		{path: "/orders/923457/deploymentAssets?cadence=stable&version=2026.01&release=..%2F..%2Fx",
+			status: http.StatusBadRequest},
+		{path: "/orders/923457/license?cadence=stable&version=2026.01%3Fx%3D1", status: http.StatusBadRequest},
+		{path: "/orders/999999/certificates", status: http.StatusNotFound},
+		{path: "/orders/923457/license?cadence=stable&version=1999.01", status: http.StatusNotFound},
+		{path: "/orders/923457/license?cadence=lts&version=2024.09", status: http.StatusBadRequest},
+		{path: "/orders/923457/certificates", fault: &orderstest.Fault{Status: http.StatusUnauthorized},
+			status: http.StatusForbidden},
+		{path: "/orders/923457/certificates", fault: &orderstest.Fault{Status: http.StatusTooManyRequests},
+			status: http.StatusTooManyRequests},
+		{path: "/orders/923457/certificates", fault: &orderstest.Fault{Status: http.StatusServiceUnavailable},
+			status: http.StatusBadGateway},
+	} {
+		api.ClearFaults()
+		if tc.fault != nil {
+			api.InjectFault(*tc.fault)
+		}
+		r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		srv.srv.Handler.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("GET %s (fault %v) returned %d, want %d: %s", tc.path, tc.fault, w.Code, tc.status, w.Body)
+		}
+		if tc.status == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "1" {
+			t.Errorf("GET %s returned Retry-After %q, want 1", tc.path, w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestServeAssetTokenFailure(t *testing.T) {
+	api, err := orderstest.NewServer(orderstest.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.Close()
+	assetreqs.SetAPIHost(api.URL)
+	defer assetreqs.SetAPIHost("")
+	authn.SetAPIHost(api.URL)
+	defer authn.SetAPIHost("")
+
+	// Bearer tokens are got as the serve command gets them, when a request needs one.
+	ts, err := authn.TokenSource("id", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(Config{
+		Tokens: []string{"secret"},
+		NewReq: func(assetName, orderNum, cadenceName, cadenceVer, cadenceRel string) (assetreqs.AssetReq, error) {
+			tok, err := ts.Token()
+			if err != nil {
+				return assetreqs.AssetReq{}, err
+			}
+			return assetreqs.New("apigee", tok.AccessToken, "id", "secret", assetName, orderNum, cadenceName,
+				cadenceVer, cadenceRel, "", "", "json", false), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once a token has been got, it is used until it expires, so the request that succeeds comes last.
+	for _, tc := range []struct {
+		fault  *orderstest.Fault
+		status int
+	}{
+		{&orderstest.Fault{Path: "token", Status: http.StatusUnauthorized}, http.StatusForbidden},
+		{&orderstest.Fault{Path: "token", Status: http.StatusTooManyRequests}, http.StatusTooManyRequests},
+		{&orderstest.Fault{Path: "token", Status: http.StatusServiceUnavailable}, http.StatusBadGateway},
+		{nil, http.StatusOK},
+	} {
+		api.ClearFaults()
+		if tc.fault != nil {
+			api.InjectFault(*tc.fault)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/orders/923457/certificates", nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		srv.srv.Handler.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Errorf("GET with token request fault %v returned %d, want %d: %s", tc.fault, w.Code, tc.status, w.Body)
+		}
+	}
+}
+
+func TestServeAssetExpiry(t *testing.T) {
+	// Licenses and certificates from the mock expire in 10 days, within the 30 days that are warned about.
+	api, err := orderstest.NewServer(orderstest.Config{Expiry: 10 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.Close()
+	assetreqs.SetAPIHost(api.URL)
+	defer assetreqs.SetAPIHost("")
+
+	var mu sync.Mutex
+	var sent []notify.Event
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notify.Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		sent = append(sent, e)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+	n, err := notify.New([]notify.Target{{URL: hook.URL}}, 30, apiclient.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := New(Config{
+		Tokens: []string{"secret"},
+		NewReq: func(assetName, orderNum, cadenceName, cadenceVer, cadenceRel string) (assetreqs.AssetReq, error) {
+			return assetreqs.New("apim", "", "id", "secret", assetName, orderNum, cadenceName, cadenceVer, cadenceRel,
+				"", "", "json", false).WithNotifier(n), nil
+		},
+		Notifier: n,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assets that are sent to the caller, rather than saved to a file, are checked as they were sent.
+	for _, path := range []string{"/orders/923457/license?cadence=stable&version=2026.01",
+		"/orders/923457/certificates", "/orders/923457/deploymentAssets?cadence=stable"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		srv.srv.Handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s returned %d: %s", path, w.Code, w.Body)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var got []string
+	for _, e := range sent {
+		got = append(got, e.Event+" "+e.AssetName)
+		if e.Event == notify.EventExpiry && (e.ExpiresAt == nil || time.Until(*e.ExpiresAt) > 11*24*time.Hour) {
+			t.Errorf("the expiry notification for the %s has the expiry %v", e.AssetName, e.ExpiresAt)
+		}
+	}
+	want := []string{"success license", "expiry license", "success certificates", "expiry certificates",
+		"success deploymentAssets"}
+	if !slices.Equal(got, want) {
+		t.Errorf("serving the assets sent the notifications %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package watch keeps track of the last cadence release seen for an order and runs actions when a new one appears.
+package watch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// State is what is remembered between checks for new releases.
+type State struct {
+	OrderNumber    string    `json:"orderNumber"`
+	CadenceName    string    `json:"cadenceName"`
+	CadenceVersion string    `json:"cadenceVersion,omitempty"`
+	Cadence        string    `json:"cadence"`
+	CadenceRelease string    `json:"cadenceRelease"`
+	AssetLocation  string    `json:"assetLocation"`
+	LastChecked    time.Time `json:"lastChecked"`
+	LastChanged    time.Time `json:"lastChanged"`
+}
+
+// Event describes a new release, as passed to a hook command and posted to a webhook.
+type Event struct {
+	Event           string `json:"event"` // always newRelease
+	OrderNumber     string `json:"orderNumber"`
+	Cadence         string `json:"cadence"`
+	CadenceRelease  string `json:"cadenceRelease"`
+	PreviousRelease string `json:"previousRelease"`
+	AssetLocation   string `json:"assetLocation"`
+}
+
+// DefaultStateFile returns the state file that is used for the given order, cadence name, and cadence version when
+// none is given: one in the watch directory of the user's config directory.
+func DefaultStateFile(orderNum, cadenceName, cadenceVer string) (string, error) {
+	d, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.New("ERROR: attempt to find the user config directory failed: " + err.Error())
+	}
+	name := orderNum + "_" + cadenceName
+	if cadenceVer != "" {
+		name += "_" + cadenceVer
+	}
+	return filepath.Join(d, "viya4-orders-cli", "watch", name+".json"), nil
+}
+
+// LoadState reads the state in the given file. If there is no such file, it returns an empty state.
+func LoadState(file string) (State, error) {
+	var s State
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, errors.New("ERROR: attempt to read watch state file " + file + " failed: " + err.Error())
+	}
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return s, errors.New("ERROR: attempt to parse watch state file " + file + " failed: " + err.Error())
+	}
+	return s, nil
+}
+
+// SaveState writes the given state to the given file, replacing what was there.
+func SaveState(file string, s State) error {
+	dir := filepath.Dir(file)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return errors.New("ERROR: attempt to create directory " + dir + " failed: " + err.Error())
+	}
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return errors.New("ERROR: json.MarshalIndent() returned: " + err.Error())
+	}
+	// Write the state next to the file first, so that an interrupted write does not lose the last release seen.
+	tmp := file + ".tmp"
+	err = os.WriteFile(tmp, append(b, '\n'), 0600)
+	if err == nil {
+		err = os.Rename(tmp, file)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return errors.New("ERROR: attempt to write watch state file " + file + " failed: " + err.Error())
+	}
+	return nil
+}
+
+// RunHook runs the given command with the shell, passing the details of the given event in VIYA4_* environment
+// variables, and in JSON on its standard input. Its output goes to STDERR, so that it is kept apart from the output of
+// the CLI.
+func RunHook(command string, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.New("ERROR: json.Marshal() returned: " + err.Error())
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"VIYA4_ORDER_NUMBER="+e.OrderNumber,
+		"VIYA4_CADENCE="+e.Cadence,
+		"VIYA4_CADENCE_RELEASE="+e.CadenceRelease,
+		"VIYA4_PREVIOUS_RELEASE="+e.PreviousRelease,
+		"VIYA4_ASSET_LOCATION="+e.AssetLocation,
+	)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	err = cmd.Run()
+	if err != nil {
+		return errors.New("ERROR: hook command " + command + " failed: " + err.Error())
+	}
+	return nil
+}
+
+// PostWebhook posts the given event as JSON to the given URL.
+func PostWebhook(url string, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return errors.New("ERROR: json.Marshal() returned: " + err.Error())
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return errors.New("ERROR: webhook request to " + url + " failed to complete: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("ERROR: webhook request to " + url + " failed: " + strings.TrimSpace(resp.Status))
+	}
+	return nil
+}
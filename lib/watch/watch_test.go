@@ -0,0 +1,122 @@
+// Copyright © 2020-2023, SAS Institute Inc., Cary, NC, USA.  All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package watch
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "watch", "923457_stable.json")
+
+	// Before the first check there is no state file, and no release has been seen.
+	s, err := LoadState(file)
+	if err != nil || s.CadenceRelease != "" {
+		t.Fatalf("LoadState of a missing file returned %+v, %v", s, err)
+	}
+
+	checked := time.Date(2026, 2, 16, 9, 36, 7, 0, time.UTC)
+	want := State{OrderNumber: "923457", CadenceName: "stable", Cadence: "Stable 2026.01",
+		CadenceRelease: "20260215.1771111111111", AssetLocation: "/home/user/sas/assets.tgz", LastChecked: checked,
+		LastChanged: checked}
+	if err = SaveState(file, want); err != nil {
+		t.Fatalf("SaveState returned %v", err)
+	}
+	if s, err = LoadState(file); err != nil || s != want {
+		t.Errorf("LoadState returned %+v, %v, want %+v", s, err, want)
+	}
+	if _, err = os.Stat(file + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("SaveState left its temporary file behind: %v", err)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("the state file has mode %v (%v), want 0600", fi.Mode().Perm(), err)
+	}
+
+	// A state file that cannot be read is an error, rather than no release seen, so actions are not run again.
+	if err = os.WriteFile(file, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadState(file); err == nil {
+		t.Error("LoadState of a corrupted file returned no error")
+	}
+}
+
+func TestDefaultStateFile(t *testing.T) {
+	for _, tc := range []struct {
+		cadenceVer, name string
+	}{
+		{"", "923457_stable.json"},
+		{"2026.01", "923457_stable_2026.01.json"},
+	} {
+		file, err := DefaultStateFile("923457", "stable", tc.cadenceVer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(file) != tc.name || filepath.Base(filepath.Dir(file)) != "watch" {
+			t.Errorf("DefaultStateFile for version %q returned %s", tc.cadenceVer, file)
+		}
+	}
+}
+
+var event = Event{Event: "newRelease", OrderNumber: "923457", Cadence: "Stable 2026.01",
+	CadenceRelease: "20260215.1771111111111", PreviousRelease: "20260127.1769510312235",
+	AssetLocation: "/home/user/sas/assets.tgz"}
+
+func TestRunHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook.out")
+	err := RunHook(`echo "$VIYA4_ORDER_NUMBER|$VIYA4_CADENCE|$VIYA4_CADENCE_RELEASE|$VIYA4_PREVIOUS_RELEASE|`+
+		`$VIYA4_ASSET_LOCATION" > `+out+` && cat >> `+out, event)
+	if err != nil {
+		t.Fatalf("RunHook returned %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, stdin, _ := strings.Cut(string(b), "\n")
+	if env != "923457|Stable 2026.01|20260215.1771111111111|20260127.1769510312235|/home/user/sas/assets.tgz" {
+		t.Errorf("the hook got the environment %s", env)
+	}
+	var e Event
+	if err = json.Unmarshal([]byte(stdin), &e); err != nil || e != event {
+		t.Errorf("the hook got %s on STDIN (%v)", stdin, err)
+	}
+
+	if err = RunHook("exit 3", event); err == nil {
+		t.Error("RunHook of a failing command returned no error")
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	var got Event
+	var status atomic.Int32
+	status.Store(http.StatusNoContent)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" ||
+			json.Unmarshal(b, &got) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	if err := PostWebhook(srv.URL, event); err != nil || got != event {
+		t.Errorf("PostWebhook returned %v after posting %+v", err, got)
+	}
+	status.Store(http.StatusInternalServerError)
+	if err := PostWebhook(srv.URL, event); err == nil {
+		t.Error("PostWebhook to a failing webhook returned no error")
+	}
+}